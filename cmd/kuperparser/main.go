@@ -0,0 +1,12 @@
+package main
+
+import (
+	"os"
+
+	"kuperparser/internal/app"
+)
+
+// kuperparser [global flags] <command> [flags] [args]; kuperparser help — список команд.
+func main() {
+	os.Exit(app.Main(os.Args[1:]))
+}
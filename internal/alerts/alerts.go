@@ -0,0 +1,163 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"kuperparser/internal/config"
+	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/atomicfile"
+)
+
+// Alerter проверяет правила после выгрузки и рассылает новые оповещения.
+//
+// Дедупликация: в state_file хранится ключ оповещения (магазин, категория,
+// правило, условие, url) и цена, с которой оно ушло. Пока условие держится
+// и цена та же — повторно не отправляется; как только условие перестало
+// выполняться, запись удаляется и следующее срабатывание уйдёт снова.
+// Если какой-то notifier не смог отправить, состояние не обновляется —
+// на следующем запуске оповещения уйдут повторно.
+type Alerter struct {
+	rules     []Rule
+	notifiers []Notifier
+	statePath string
+	log       *slog.Logger
+}
+
+// FromConfig собирает Alerter из секции alerts; без правил — nil.
+func FromConfig(cfg *config.Config, log *slog.Logger) (*Alerter, error) {
+	if log == nil {
+		log = slog.Default()
+	}
+	if len(cfg.Alerts.Rules) == 0 {
+		return nil, nil
+	}
+
+	rules, err := Compile(cfg.Alerts.Rules)
+	if err != nil {
+		return nil, err
+	}
+	notifiers, err := Notifiers(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	return &Alerter{
+		rules:     rules,
+		notifiers: notifiers,
+		statePath: cfg.Alerts.StateFile,
+		log:       log,
+	}, nil
+}
+
+// Run: prev — прошлый снимок категории (nil, если его нет), storeID — на
+// случай, если в cur нет метаданных магазина. Возвращает отправленные оповещения.
+func (a *Alerter) Run(ctx context.Context, prev *repository.CategoryResult, cur repository.CategoryResult, storeID int) ([]Alert, error) {
+	fired := Evaluate(a.rules, prev, cur, storeID)
+
+	st, err := loadState(a.statePath)
+	if err != nil {
+		return nil, fmt.Errorf("alerts state: %w", err)
+	}
+
+	scope := scopePrefix(cur, storeID)
+	active := make(map[string]bool, len(fired))
+	var fresh []Alert
+	for _, al := range fired {
+		k := al.Key()
+		if active[k] {
+			continue // дубль товара в выгрузке
+		}
+		active[k] = true
+		if st.Sent[k] == al.NewPrice {
+			continue
+		}
+		fresh = append(fresh, al)
+	}
+
+	if len(fresh) > 0 {
+		var errs []error
+		for _, n := range a.notifiers {
+			if err := n.Notify(ctx, fresh); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			return fresh, errors.Join(errs...)
+		}
+	}
+
+	// условие перестало выполняться — сбрасываем, чтобы следующее срабатывание ушло
+	for k := range st.Sent {
+		if strings.HasPrefix(k, scope) && !active[k] {
+			delete(st.Sent, k)
+		}
+	}
+	for _, al := range fresh {
+		st.Sent[al.Key()] = al.NewPrice
+	}
+	if err := st.save(a.statePath); err != nil {
+		return fresh, fmt.Errorf("alerts state: %w", err)
+	}
+
+	a.log.Info("alerts evaluated", "rules", len(a.rules), "fired", len(fired), "sent", len(fresh))
+	return fresh, nil
+}
+
+// ключи этой категории — префикс Alert.Key
+func scopePrefix(cur repository.CategoryResult, storeID int) string {
+	if cur.Store != nil && cur.Store.ID > 0 {
+		storeID = cur.Store.ID
+	}
+	categoryID := 0
+	if cur.Category != nil {
+		categoryID = cur.Category.ID
+	}
+	return strconv.Itoa(storeID) + "/" + strconv.Itoa(categoryID) + "/"
+}
+
+type state struct {
+	Sent map[string]string `json:"sent"` // ключ → цена на момент отправки
+}
+
+// без state_file дедупликация живёт только внутри запуска
+func loadState(path string) (*state, error) {
+	st := &state{Sent: make(map[string]string)}
+	if path == "" {
+		return st, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return st, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, st); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if st.Sent == nil {
+		st.Sent = make(map[string]string)
+	}
+	return st, nil
+}
+
+func (s *state) save(path string) error {
+	if path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteWith(path, atomicfile.Options{Lock: true}, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
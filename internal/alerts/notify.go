@@ -0,0 +1,113 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"kuperparser/internal/client/httpc"
+	"kuperparser/internal/client/transport"
+	"kuperparser/internal/config"
+	"kuperparser/internal/repository/webhook"
+)
+
+type Notifier interface {
+	Notify(ctx context.Context, alerts []Alert) error
+}
+
+// Notifiers собирает оповещатели из alerts.notifiers; пусто — stdout.
+func Notifiers(cfg *config.Config, log *slog.Logger) ([]Notifier, error) {
+	specs := cfg.Alerts.Notifiers
+	if len(specs) == 0 {
+		specs = []config.NotifierConfig{{Type: "stdout"}}
+	}
+
+	out := make([]Notifier, 0, len(specs))
+	for _, spec := range specs {
+		switch spec.Type {
+		case "stdout":
+			out = append(out, Writer{W: os.Stdout})
+		case "file":
+			out = append(out, File{Path: spec.Path})
+		case "webhook":
+			tr, err := transport.Build(transport.Options{
+				HTTPClient: httpc.New(time.Duration(cfg.HTTP.TimeoutSeconds) * time.Second),
+				Retries:    cfg.HTTP.Retries,
+				Logger:     log,
+			})
+			if err != nil {
+				return nil, err
+			}
+			s, err := webhook.New(webhook.Options{URL: spec.URL, Secret: spec.Secret, Transport: tr}, log)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, Webhook{Sink: s})
+		default:
+			return nil, fmt.Errorf("unknown notifier type %q (expected stdout|file|webhook)", spec.Type)
+		}
+	}
+	return out, nil
+}
+
+// Writer печатает оповещения строками для человека.
+type Writer struct {
+	W io.Writer
+}
+
+func (n Writer) Notify(_ context.Context, alerts []Alert) error {
+	var b bytes.Buffer
+	for _, a := range alerts {
+		b.WriteString("ALERT ")
+		b.WriteString(a.String())
+		b.WriteByte('\n')
+	}
+	_, err := n.W.Write(b.Bytes())
+	return err
+}
+
+// File дописывает оповещения в файл строками NDJSON.
+type File struct {
+	Path string
+}
+
+func (n File) Notify(_ context.Context, alerts []Alert) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for _, a := range alerts {
+		if err := enc.Encode(a); err != nil {
+			return err
+		}
+	}
+
+	if dir := filepath.Dir(n.Path); dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(n.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("alerts file: %w", err)
+	}
+	if _, err := f.Write(b.Bytes()); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("alerts file: %w", err)
+	}
+	return f.Close()
+}
+
+// Webhook отправляет пачку оповещений одним конвертом kind=alerts
+// (подпись и ретраи — как у webhook-выхода).
+type Webhook struct {
+	Sink *webhook.Sink
+}
+
+func (n Webhook) Notify(ctx context.Context, alerts []Alert) error {
+	return n.Sink.Send(ctx, webhook.KindAlerts, alerts, len(alerts))
+}
@@ -0,0 +1,187 @@
+package alerts
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"kuperparser/internal/config"
+	"kuperparser/internal/diff"
+	"kuperparser/internal/domain/models"
+	"kuperparser/internal/repository"
+)
+
+const (
+	TriggerChange      = "change_percent"
+	TriggerPriceBelow  = "price_below"
+	TriggerBackInStock = "back_in_stock"
+)
+
+type Alert struct {
+	Rule       string   `json:"rule"`
+	Trigger    string   `json:"trigger"`
+	StoreID    int      `json:"store_id"`
+	CategoryID int      `json:"category_id,omitempty"`
+	URL        string   `json:"url"`
+	Name       string   `json:"name"`
+	OldPrice   string   `json:"old_price,omitempty"`
+	NewPrice   string   `json:"new_price"`
+	Percent    *float64 `json:"percent,omitempty"`
+	Threshold  float64  `json:"threshold,omitempty"` // change_percent/price_below из правила
+	FetchedAt  string   `json:"fetched_at"`
+}
+
+// Key — одно и то же условие для одного товара; по нему идёт дедупликация.
+func (a Alert) Key() string {
+	return fmt.Sprintf("%d/%d/%s/%s/%s", a.StoreID, a.CategoryID, a.Rule, a.Trigger, a.URL)
+}
+
+func (a Alert) String() string {
+	switch a.Trigger {
+	case TriggerChange:
+		pct := ""
+		if a.Percent != nil {
+			pct = fmt.Sprintf(" (%+.2f%%)", *a.Percent)
+		}
+		return fmt.Sprintf("[%s] %s: %s → %s%s %s", a.Rule, a.Name, a.OldPrice, a.NewPrice, pct, a.URL)
+	case TriggerPriceBelow:
+		return fmt.Sprintf("[%s] %s: price %s is below %v %s", a.Rule, a.Name, a.NewPrice, a.Threshold, a.URL)
+	default:
+		return fmt.Sprintf("[%s] %s: back in stock at %s %s", a.Rule, a.Name, a.NewPrice, a.URL)
+	}
+}
+
+type Rule struct {
+	cfg  config.AlertRule
+	name string
+	url  *regexp.Regexp
+	re   *regexp.Regexp
+}
+
+// Compile готовит правила из конфига; безымянные получают имя rules[i].
+func Compile(cfgs []config.AlertRule) ([]Rule, error) {
+	out := make([]Rule, 0, len(cfgs))
+	for i, c := range cfgs {
+		r := Rule{cfg: c, name: c.Name}
+		if r.name == "" {
+			r.name = fmt.Sprintf("rules[%d]", i)
+		}
+		if c.URL != "" {
+			// * — любая подстрока, остальное буквально
+			parts := strings.Split(c.URL, "*")
+			for j := range parts {
+				parts[j] = regexp.QuoteMeta(parts[j])
+			}
+			r.url = regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+		}
+		if c.Pattern != "" {
+			re, err := regexp.Compile("(?i)" + c.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("alert rule %s: pattern: %w", r.name, err)
+			}
+			r.re = re
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func (r Rule) matches(storeID int, p models.Product) bool {
+	if r.cfg.StoreID > 0 && r.cfg.StoreID != storeID {
+		return false
+	}
+	if r.url != nil && !r.url.MatchString(p.URL) {
+		return false
+	}
+	if r.re != nil && !r.re.MatchString(p.Name) {
+		return false
+	}
+	return true
+}
+
+// Evaluate проверяет правила на текущем результате; prev — прошлый снимок
+// той же категории или nil (тогда срабатывает только price_below).
+func Evaluate(rules []Rule, prev *repository.CategoryResult, cur repository.CategoryResult, storeID int) []Alert {
+	if cur.Store != nil && cur.Store.ID > 0 {
+		storeID = cur.Store.ID
+	}
+	categoryID := 0
+	if cur.Category != nil {
+		categoryID = cur.Category.ID
+	}
+
+	var (
+		before  map[string]models.Product
+		changes map[string]diff.PriceChange
+	)
+	if prev != nil {
+		before = make(map[string]models.Product, len(prev.Products))
+		for _, p := range prev.Products {
+			before[p.Key()] = p
+		}
+		changes = make(map[string]diff.PriceChange)
+		for _, c := range diff.Categories(*prev, cur).PriceChanges {
+			changes[c.URL] = c
+		}
+	}
+
+	var out []Alert
+	for _, p := range cur.Products {
+		for _, r := range rules {
+			if !r.matches(storeID, p) {
+				continue
+			}
+			base := Alert{
+				Rule:       r.name,
+				StoreID:    storeID,
+				CategoryID: categoryID,
+				URL:        p.URL,
+				Name:       p.Name,
+				NewPrice:   p.Price,
+				FetchedAt:  cur.FetchedAt,
+			}
+			price, priced := parsePrice(p.Price)
+
+			if r.cfg.ChangePercent > 0 && prev != nil {
+				if c, ok := changes[p.URL]; ok && c.Percent != nil && r.direction(*c.Percent) &&
+					math.Abs(*c.Percent) >= r.cfg.ChangePercent {
+					a := base
+					a.Trigger, a.OldPrice, a.Percent, a.Threshold = TriggerChange, c.OldPrice, c.Percent, r.cfg.ChangePercent
+					out = append(out, a)
+				}
+			}
+			if r.cfg.PriceBelow > 0 && priced && price < r.cfg.PriceBelow {
+				a := base
+				a.Trigger, a.Threshold = TriggerPriceBelow, r.cfg.PriceBelow
+				out = append(out, a)
+			}
+			if r.cfg.BackInStock && prev != nil && priced {
+				old, ok := before[p.Key()]
+				if _, wasPriced := parsePrice(old.Price); !ok || !wasPriced {
+					a := base
+					a.Trigger, a.OldPrice = TriggerBackInStock, old.Price
+					out = append(out, a)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func (r Rule) direction(pct float64) bool {
+	switch r.cfg.Direction {
+	case "down":
+		return pct < 0
+	case "up":
+		return pct > 0
+	default:
+		return true
+	}
+}
+
+func parsePrice(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil && v > 0
+}
@@ -0,0 +1,226 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"kuperparser/internal/apis/kuper"
+	"kuperparser/internal/repository"
+)
+
+// department — раздел, который листается целиком; товары раскладываются
+// по его листовым категориям по _department_slug.
+type department struct {
+	slug   string
+	path   []kuper.Category
+	leaves map[string][]kuper.Category // slug листа → путь от корня
+}
+
+// CatalogFunc получает товары раздела целиком, после его последней страницы.
+// Ошибка из CatalogFunc прерывает обход.
+type CatalogFunc func(products []repository.CatalogProduct) error
+
+// Catalog обходит всё дерево категорий магазина: каждый раздел загружается
+// один раз, товар попадает в свою листовую категорию, а товар из нескольких
+// категорий раздела остаётся одной записью со списком категорий.
+// В памяти держится только текущий раздел: его товары уходят в fn, когда
+// раздел загружен целиком, поэтому от упавшего раздела в fn не приходит
+// ничего — он перечислен в FailedDepartments. Отмена контекста прерывает
+// обход с ошибкой; загруженное из прерванного раздела перед этим отдаётся в fn.
+// Products в результате пуст, Count — сколько товаров отдано;
+// FetchedAt и Store заполняет вызывающий.
+func (s *CategoryProductsService) Catalog(ctx context.Context, storeID int, fn CatalogFunc) (repository.CatalogResult, error) {
+	var res repository.CatalogResult
+	if storeID <= 0 {
+		return res, fmt.Errorf("storeID must be > 0")
+	}
+
+	cats, err := s.kuper.ListCategories(ctx, storeID)
+	if err != nil {
+		return res, fmt.Errorf("list categories: %w", err)
+	}
+	res.Categories = CategoryNodes(cats)
+
+	depts := departments(cats)
+	res.Departments = len(depts)
+	s.log.Info("fetch store catalog", "store_id", storeID, "departments", len(depts))
+
+	unknownTotal := 0
+	for i, d := range depts {
+		var (
+			products []repository.CatalogProduct
+			index    = make(map[string]int) // Product.Key → позиция в products
+			unknown  = make(map[string]int) // slug листа вне дерева → товаров
+		)
+		err := s.eachPage(ctx, storeID, d.slug, func(items []Item) error {
+			for _, it := range items {
+				ref, ok := d.ref(it.Leaf)
+				if !ok {
+					unknown[it.Leaf]++
+				}
+
+				k := it.Product.Key()
+				if at, seen := index[k]; seen {
+					products[at].Categories = repository.AppendRef(products[at].Categories, ref)
+					continue
+				}
+				index[k] = len(products)
+				products = append(products, repository.CatalogProduct{Product: it.Product, Categories: []repository.CategoryRef{ref}})
+			}
+			if len(products) > 1_000_000 {
+				return fmt.Errorf("too many products parsed: possible infinite pagination")
+			}
+			return nil
+		})
+		if len(unknown) > 0 {
+			n := 0
+			for _, c := range unknown {
+				n += c
+			}
+			unknownTotal += n
+			s.log.Warn("products in categories missing from the tree",
+				"store_id", storeID,
+				"slug", d.slug,
+				"count", n,
+				"leaves", sortedKeys(unknown),
+			)
+		}
+		if err != nil {
+			if StopCause(ctx) != nil {
+				if werr := s.flush(fn, products, &res); werr != nil {
+					return res, werr
+				}
+				return res, fmt.Errorf("department %d/%d: %w", i+1, len(depts), err)
+			}
+			s.log.Warn("department failed (continue)", "store_id", storeID, "slug", d.slug, "err", err)
+			res.FailedDepartments = append(res.FailedDepartments, d.slug)
+			continue
+		}
+		if err := s.flush(fn, products, &res); err != nil {
+			return res, err
+		}
+		s.log.Info("department fetched", "store_id", storeID, "slug", d.slug, "count", len(products), "progress", fmt.Sprintf("%d/%d", i+1, len(depts)))
+	}
+
+	if len(depts) > 0 && len(res.FailedDepartments) == len(depts) {
+		return res, fmt.Errorf("all %d departments failed", len(depts))
+	}
+
+	s.log.Info("store catalog fetched",
+		"store_id", storeID,
+		"departments", len(depts),
+		"failed", len(res.FailedDepartments),
+		"unknown_leaves", unknownTotal,
+		"count", res.Count,
+	)
+	return res, nil
+}
+
+// flush отдаёт товары раздела в fn и учитывает их в Count.
+func (s *CategoryProductsService) flush(fn CatalogFunc, products []repository.CatalogProduct, res *repository.CatalogResult) error {
+	if len(products) == 0 {
+		return nil
+	}
+	if err := fn(products); err != nil {
+		return fmt.Errorf("write products: %w", err)
+	}
+	res.Count += len(products)
+	return nil
+}
+
+// departments: раздел листа — ближайший предок с детьми (как в
+// ResolveDepartmentAndLeafSlug); лист в корне и раздел без загруженных
+// детей листаются сами по себе.
+func departments(cats []kuper.Category) []*department {
+	var (
+		out    []*department
+		bySlug = make(map[string]*department)
+	)
+	get := func(path []kuper.Category) *department {
+		last := path[len(path)-1]
+		if d, ok := bySlug[last.Slug]; ok {
+			return d
+		}
+		d := &department{slug: last.Slug, path: path, leaves: make(map[string][]kuper.Category)}
+		bySlug[last.Slug] = d
+		out = append(out, d)
+		return d
+	}
+
+	var walk func(cats []kuper.Category, path []kuper.Category)
+	walk = func(cats []kuper.Category, path []kuper.Category) {
+		for _, c := range cats {
+			if c.Slug == "" {
+				continue
+			}
+			p := append(append([]kuper.Category{}, path...), c)
+
+			switch {
+			case len(c.Children) > 0:
+				walk(c.Children, p)
+			case c.HasChildren || len(path) == 0:
+				get(p)
+			default:
+				get(path).leaves[c.Slug] = p
+			}
+		}
+	}
+	walk(cats, nil)
+	return out
+}
+
+// ref — категория товара по его _department_slug. Товары самого раздела
+// (slug раздела или пустой slug у раздела без листьев) лежат в разделе.
+// Лист, которого нет в дереве, не угадывается: ref без id, со slug листа
+// и путём раздела, дополненным этим slug; ok=false.
+func (d *department) ref(leaf string) (repository.CategoryRef, bool) {
+	if path, ok := d.leaves[leaf]; ok {
+		return categoryRef(path), true
+	}
+	ref := categoryRef(d.path)
+	if leaf == d.slug || (leaf == "" && len(d.leaves) == 0) {
+		return ref, true
+	}
+	ref.ID, ref.Slug = 0, leaf
+	if leaf != "" {
+		ref.Path = append(ref.Path, leaf)
+	}
+	return ref, false
+}
+
+func categoryRef(path []kuper.Category) repository.CategoryRef {
+	last := path[len(path)-1]
+	names := make([]string, len(path))
+	for i, c := range path {
+		names[i] = c.Name
+	}
+	return repository.CategoryRef{ID: last.ID, Slug: last.Slug, Path: names}
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CategoryNodes раскладывает дерево в плоский список; parent берётся из
+// вложенности, если API не прислал parent_id.
+func CategoryNodes(cats []kuper.Category) []repository.CategoryNode {
+	return categoryNodes(cats, 0, nil)
+}
+
+func categoryNodes(cats []kuper.Category, parent int, out []repository.CategoryNode) []repository.CategoryNode {
+	for _, c := range cats {
+		p := c.ParentID
+		if p == 0 {
+			p = parent
+		}
+		out = append(out, repository.CategoryNode{ID: c.ID, ParentID: p, Name: c.Name, Slug: c.Slug})
+		out = categoryNodes(c.Children, c.ID, out)
+	}
+	return out
+}
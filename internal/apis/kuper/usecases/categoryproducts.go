@@ -9,6 +9,7 @@ import (
 
 	"kuperparser/internal/domain/models"
 	"log/slog"
+	"time"
 )
 
 type CategoryProductsService struct {
@@ -18,6 +19,8 @@ type CategoryProductsService struct {
 	perPage     int
 	offersLimit int
 	maxPages    int
+	pageTimeout time.Duration
+	progress    Progress
 }
 
 func NewCategoryProductsService(
@@ -108,27 +111,40 @@ func findPathByID(cats []kuper.Category, id int) ([]kuper.Category, bool) {
 	return nil, false
 }
 
+// PageFunc получает товары очередной страницы сразу по мере загрузки.
+// Ошибка из PageFunc прерывает обход.
+type PageFunc func(products []models.Product) error
+
 func (s *CategoryProductsService) GetByCategoryID(ctx context.Context, storeID int, categoryID int) ([]models.Product, string, error) {
-	deptSlug, leafSlug, err := s.ResolveDepartmentAndLeafSlug(ctx, storeID, categoryID)
+	out := make([]models.Product, 0, 128)
+	slug, _, err := s.StreamByCategoryID(ctx, storeID, categoryID, func(page []models.Product) error {
+		out = append(out, page...)
+		return nil
+	})
 	if err != nil {
-		return nil, "", err
+		return nil, slug, err
 	}
+	return out, slug, nil
+}
 
-	products, err := s.GetByDepartmentSlug(ctx, storeID, deptSlug, leafSlug)
+// StreamByCategoryID — как GetByCategoryID, но не копит товары в памяти:
+// отдаёт их постранично в fn. Возвращает использованный slug и число товаров.
+func (s *CategoryProductsService) StreamByCategoryID(ctx context.Context, storeID int, categoryID int, fn PageFunc) (string, int, error) {
+	deptSlug, leafSlug, err := s.ResolveDepartmentAndLeafSlug(ctx, storeID, categoryID)
 	if err != nil {
-		used := deptSlug
-		if leafSlug != "" {
-			used = leafSlug
-		}
-		return nil, used, err
+		return "", 0, err
 	}
 
 	used := deptSlug
 	if leafSlug != "" {
 		used = leafSlug
 	}
-	return products, used, nil
 
+	count, err := s.StreamByDepartmentSlug(ctx, storeID, deptSlug, leafSlug, fn)
+	if err != nil {
+		return used, count, err
+	}
+	return used, count, nil
 }
 
 func (s *CategoryProductsService) GetByDepartmentSlug(
@@ -137,11 +153,29 @@ func (s *CategoryProductsService) GetByDepartmentSlug(
 	departmentSlug string,
 	onlyChildSlug string,
 ) ([]models.Product, error) {
+	out := make([]models.Product, 0, 128)
+	_, err := s.StreamByDepartmentSlug(ctx, storeID, departmentSlug, onlyChildSlug, func(page []models.Product) error {
+		out = append(out, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *CategoryProductsService) StreamByDepartmentSlug(
+	ctx context.Context,
+	storeID int,
+	departmentSlug string,
+	onlyChildSlug string,
+	fn PageFunc,
+) (int, error) {
 	if storeID <= 0 {
-		return nil, fmt.Errorf("storeID must be > 0")
+		return 0, fmt.Errorf("storeID must be > 0")
 	}
 	if departmentSlug == "" {
-		return nil, fmt.Errorf("departmentSlug must not be empty")
+		return 0, fmt.Errorf("departmentSlug must not be empty")
 	}
 
 	s.log.Info("fetch category products",
@@ -152,61 +186,200 @@ func (s *CategoryProductsService) GetByDepartmentSlug(
 		"offers_limit", s.offersLimit,
 	)
 
-	out := make([]models.Product, 0, 128)
 	total := 0
+	err := s.eachPage(ctx, storeID, departmentSlug, func(items []Item) error {
+		batch := make([]models.Product, 0, len(items))
+		for _, it := range items {
+			if onlyChildSlug == "" || it.Leaf == onlyChildSlug {
+				batch = append(batch, it.Product)
+			}
+		}
 
-	for page := 1; page <= s.maxPages; page++ {
-		if err := ctx.Err(); err != nil {
-			return nil, err
+		total += len(batch)
+		if total > 200_000 {
+			return errors.New("too many products parsed: possible infinite pagination")
 		}
+		if len(batch) > 0 {
+			return fn(batch)
+		}
+		return nil
+	})
+	if err != nil {
+		return total, err
+	}
+
+	s.log.Info("category products fetched",
+		"store_id", storeID,
+		"department_slug", departmentSlug,
+		"only_child_slug", onlyChildSlug,
+		"count", total,
+	)
+
+	return total, nil
+}
+
+// Item — товар страницы раздела вместе со slug его листовой категории.
+type Item struct {
+	models.Product
+	Leaf string `json:"leaf,omitempty"`
+}
+
+// Progress сохраняет загруженные страницы разделов, чтобы прерванный обход
+// продолжился с места остановки (реализация — internal/checkpoint).
+type Progress interface {
+	// Resume отдаёт в fn уже загруженные страницы раздела (по одной, без
+	// накопления в памяти) и возвращает номер последней из них и признак,
+	// что раздел пройден целиком.
+	Resume(departmentSlug string, fn func(items []Item) error) (page int, done bool, err error)
+	SavePage(departmentSlug string, page int, items []Item) error
+	Done(departmentSlug string) error
+}
+
+// WithProgress — копия сервиса, которая продолжает разделы из p и
+// сохраняет в него каждую страницу; транспорт и настройки общие.
+func (s *CategoryProductsService) WithProgress(p Progress) *CategoryProductsService {
+	c := *s
+	c.progress = p
+	return &c
+}
+
+// WithPageTimeout — копия сервиса с дедлайном на одну страницу (вместе
+// с ретраями транспорта); 0 — без лимита.
+func (s *CategoryProductsService) WithPageTimeout(d time.Duration) *CategoryProductsService {
+	c := *s
+	c.pageTimeout = d
+	return &c
+}
+
+type stopKey struct{}
 
-		raw, err := s.kuper.ListProducts(ctx, storeID, departmentSlug, page, s.perPage, s.offersLimit)
+// WithStop — ctx обхода с мягкой остановкой: после отмены stop новые
+// страницы не начинаются, а начатая дожидается. Запросы по-прежнему
+// обрывает только отмена самого ctx (дедлайн, отключение клиента).
+func WithStop(ctx, stop context.Context) context.Context {
+	return context.WithValue(ctx, stopKey{}, stop)
+}
+
+// StopCause — почему обход остановлен: context.Cause отменённого ctx или
+// его stop (WithStop); nil — не останавливали.
+func StopCause(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	if stop, ok := ctx.Value(stopKey{}).(context.Context); ok && stop.Err() != nil {
+		return context.Cause(stop)
+	}
+	return nil
+}
+
+// stopErr — как StopCause, но ctx.Err() (без подробностей причины).
+func stopErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if stop, ok := ctx.Value(stopKey{}).(context.Context); ok {
+		return stop.Err()
+	}
+	return nil
+}
+
+// eachPage листает раздел до пустой/неполной страницы или maxPages
+// и отдаёт товары каждой страницы в fn. С Progress сначала отдаются
+// сохранённые товары, и обход идёт со следующей страницы. При отмене ctx
+// или его stop между страницами возвращает ошибку с номером страницы,
+// до которой дошёл.
+func (s *CategoryProductsService) eachPage(ctx context.Context, storeID int, departmentSlug string, fn func(items []Item) error) error {
+	start := 1
+	if s.progress != nil {
+		n := 0
+		page, done, err := s.progress.Resume(departmentSlug, func(items []Item) error {
+			n += len(items)
+			return fn(items)
+		})
 		if err != nil {
-			return nil, fmt.Errorf("list products slug=%s page=%d: %w", departmentSlug, page, err)
+			return fmt.Errorf("resume slug=%s: %w", departmentSlug, err)
+		}
+		if page > 0 {
+			s.log.Info("resume department from checkpoint",
+				"store_id", storeID,
+				"department_slug", departmentSlug,
+				"pages", page,
+				"products", n,
+				"done", done,
+			)
+		}
+		if done {
+			return nil
+		}
+		start = page + 1
+	}
+
+	for page := start; page <= s.maxPages; page++ {
+		if err := stopErr(ctx); err != nil {
+			return fmt.Errorf("stopped before page %d of slug=%s: %w", page, departmentSlug, err)
+		}
+
+		raw, err := s.listPage(ctx, storeID, departmentSlug, page)
+		if err != nil {
+			return fmt.Errorf("list products slug=%s page=%d: %w", departmentSlug, page, err)
 		}
 
 		rawLen := len(raw)
 		if rawLen == 0 {
 			break
 		}
-		if onlyChildSlug != "" {
-			filtered := raw[:0]
-			for _, p := range raw {
-				if p.Raw == nil {
-					continue
-				}
-				if v, ok := p.Raw["_department_slug"].(string); ok && v == onlyChildSlug {
-					filtered = append(filtered, p)
-				}
-			}
-			raw = filtered
-		}
-
+		items := make([]Item, 0, rawLen)
 		for _, p := range raw {
-			dp := mapper.FromProduct(s.baseURL, p)
-			if dp.Name == "" && dp.URL == "" && dp.Price == "" {
-				continue
+			if dp, ok := s.mapProduct(p); ok {
+				items = append(items, Item{Product: dp, Leaf: departmentSlugOf(p)})
 			}
-			out = append(out, dp)
-			total++
-			if total > 200_000 {
-				return nil, errors.New("too many products parsed: possible infinite pagination")
+		}
+		if err := fn(items); err != nil {
+			return err
+		}
+		if s.progress != nil {
+			if err := s.progress.SavePage(departmentSlug, page, items); err != nil {
+				return fmt.Errorf("checkpoint: %w", err)
 			}
 		}
-
 		if rawLen < s.perPage {
 			break
 		}
 	}
 
-	s.log.Info("category products fetched",
-		"store_id", storeID,
-		"department_slug", departmentSlug,
-		"only_child_slug", onlyChildSlug,
-		"count", len(out),
-	)
+	if s.progress != nil {
+		if err := s.progress.Done(departmentSlug); err != nil {
+			return fmt.Errorf("checkpoint: %w", err)
+		}
+	}
+	return nil
+}
 
-	return out, nil
+// listPage — одна страница вместе с ретраями транспорта, не дольше pageTimeout.
+func (s *CategoryProductsService) listPage(ctx context.Context, storeID int, departmentSlug string, page int) ([]kuper.Product, error) {
+	if s.pageTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.pageTimeout)
+		defer cancel()
+	}
+	return s.kuper.ListProducts(ctx, storeID, departmentSlug, page, s.perPage, s.offersLimit)
+}
+
+func (s *CategoryProductsService) mapProduct(p kuper.Product) (models.Product, bool) {
+	dp := mapper.FromProduct(s.baseURL, p)
+	if dp.Name == "" && dp.URL == "" && dp.Price == "" {
+		return dp, false
+	}
+	return dp, true
+}
+
+// departmentSlugOf — slug листовой категории товара внутри раздела.
+func departmentSlugOf(p kuper.Product) string {
+	if p.Raw == nil {
+		return ""
+	}
+	v, _ := p.Raw["_department_slug"].(string)
+	return v
 }
 
 func (s *CategoryProductsService) GetBySlug(ctx context.Context, storeID int, slug string) ([]models.Product, error) {
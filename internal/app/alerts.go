@@ -0,0 +1,40 @@
+package app
+
+import (
+	"log/slog"
+	"time"
+
+	"kuperparser/internal/config"
+	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/history"
+	"kuperparser/internal/repository/reader"
+)
+
+// previousSnapshot — прошлый снимок категории для правил оповещений:
+// последний запуск из истории цен, иначе прежний output_file (если его
+// можно прочитать). Читается до выгрузки, пока файл не перезаписан.
+func previousSnapshot(cfg *config.Config, log *slog.Logger) *repository.CategoryResult {
+	if cfg.History.Dir != "" {
+		res, err := history.New(cfg.History.Dir, log).Snapshot(cfg.Kuper.StoreID, cfg.CLI.CategoryID, time.Now())
+		if err == nil {
+			return &res
+		}
+		log.Debug("no previous snapshot in history", "err", err)
+	}
+
+	if cfg.CLI.OutputFile != "" {
+		res, err := reader.LoadCategory(cfg.CLI.OutputFile)
+		switch {
+		case err != nil:
+			log.Debug("no previous output to compare with", "err", err)
+		case res.Category != nil && res.Category.ID != cfg.CLI.CategoryID,
+			res.Store != nil && res.Store.ID != cfg.Kuper.StoreID:
+			log.Debug("previous output is for another store/category, ignored", "path", cfg.CLI.OutputFile)
+		default:
+			return &res
+		}
+	}
+
+	log.Info("no previous snapshot, only price_below alerts can fire")
+	return nil
+}
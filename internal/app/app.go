@@ -0,0 +1,289 @@
+package app
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"kuperparser/internal/bootstrap"
+	"kuperparser/internal/config"
+	"kuperparser/internal/logger"
+)
+
+// Единый бинарник kuperparser: подкоманды с общими глобальными флагами
+// конфига (их можно писать и до, и после имени команды), одинаковыми кодами
+// выхода и встроенной справкой. Прежние kuperparser-api, kuperparser-cli и
+// kuperparser-stores-scan — обёртки над теми же командами.
+
+// Коды выхода всех команд; остановка по Ctrl-C — bootstrap.ExitInterrupted.
+const (
+	exitOK      = 0
+	exitFailure = 1 // ошибка выполнения
+	exitUsage   = 2 // неверные флаги или аргументы
+)
+
+type command struct {
+	name    string // "crawl", "stores scan"
+	args    string // аргументы после флагов, для справки
+	summary string
+	help    string // подробности для -h, может быть пустым
+	// run получает FlagSet с уже зарегистрированными глобальными флагами,
+	// добавляет свои и разбирает args первым делом (так работает -h)
+	run func(g *globals, fs *flag.FlagSet, args []string) int
+}
+
+var commands = []*command{
+	{name: "serve", summary: "HTTP API: /categories, /products, /history, /admin/proxies", help: serveHelp, run: runServe},
+	{name: "categories", summary: "дерево категорий магазина", run: runCategories},
+	{name: "products", summary: "товары категории (как GET /products)", run: runProducts},
+	{name: "store", summary: "информация о магазине", run: runStore},
+	{name: "stores scan", summary: "перебор storeID и выгрузка найденных магазинов", help: storesScanHelp, run: runStoresScan},
+	{name: "crawl", summary: "выгрузка категории или всего каталога (-all) в выходы cli", help: crawlHelp, run: runCrawl},
+	{name: "batch", args: "JOBS", summary: "пакет заданий магазин + категория пулом воркеров", help: batchHelp, run: runBatch},
+	{name: "history prices", summary: "история цен товара", help: historyHelp, run: runHistoryPrices},
+	{name: "history snapshot", summary: "снимок категории на момент времени", help: historyHelp, run: runHistorySnapshot},
+	{name: "diff", args: "OLD NEW", summary: "сравнение двух выгрузок", help: diffHelp, run: runDiff},
+	{name: "schema", args: "[name]", summary: "JSON Schema выходных форматов", run: runSchema},
+	{name: "config validate", summary: "проверка config.yaml (для CI)", run: runConfigValidate},
+	{name: "proxies check", summary: "проверка прокси из конфига", run: runProxiesCheck},
+}
+
+// globals — глобальные флаги, общие для всех команд.
+type globals struct {
+	cf *config.Flags
+	// level — уровень логов; serve меняет его при перезагрузке конфига
+	level *slog.LevelVar
+}
+
+func newGlobals(fs *flag.FlagSet) *globals {
+	return &globals{cf: config.BindFlags(fs), level: new(slog.LevelVar)}
+}
+
+// Main — kuperparser [global flags] <command> [flags] [args]; возвращает код выхода.
+func Main(args []string) int {
+	fs := flag.NewFlagSet("kuperparser", flag.ContinueOnError)
+	g := newGlobals(fs)
+	fs.Usage = func() { writeUsage(fs.Output()) }
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		if g.cf.PrintConfig {
+			_, _, code := g.setup()
+			return code
+		}
+		writeUsage(os.Stderr)
+		return exitUsage
+	}
+	return g.dispatch(fs.Args())
+}
+
+// LegacyCLI — порядок аргументов прежнего kuperparser-cli: глобальные флаги и
+// флаги выгрузки (-storeID, -categoryID, -out, ...), затем необязательная
+// команда (config, schema, diff, proxies, history, batch). Без команды — crawl.
+func LegacyCLI(args []string) int {
+	fs := flag.NewFlagSet("kuperparser-cli", flag.ContinueOnError)
+	g := newGlobals(fs)
+	cf := bindCrawlFlags(g, fs)
+	g.cf.Alias(fs, "storeID", "kuper.store_id", "override storeID (optional)")
+	g.cf.Alias(fs, "categoryID", "cli.category_id", "override categoryID (optional)")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		return crawl(g, cf)
+	}
+	return g.dispatch(fs.Args())
+}
+
+// dispatch находит команду по одному или двум словам и запускает её.
+func (g *globals) dispatch(args []string) int {
+	if args[0] == "help" {
+		return g.help(args[1:])
+	}
+	c, rest := lookup(args)
+	if c == nil {
+		return unknown(os.Stderr, args[0])
+	}
+
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	g.cf.Bind(fs)
+	fs.Usage = func() { c.usage(fs.Output(), fs) }
+	return c.run(g, fs, rest)
+}
+
+// help: kuperparser help [command | flags]
+func (g *globals) help(args []string) int {
+	if len(args) == 0 {
+		writeUsage(os.Stdout)
+		return exitOK
+	}
+	if args[0] == "flags" {
+		fs := flag.NewFlagSet("kuperparser", flag.ContinueOnError)
+		config.BindFlags(fs)
+		fs.SetOutput(os.Stdout)
+		fmt.Println("global flags (before or after the command):")
+		fs.PrintDefaults()
+		return exitOK
+	}
+	c, _ := lookup(args)
+	if c == nil {
+		return unknown(os.Stdout, args[0])
+	}
+
+	// справку печатает разбор -h, флаги команды регистрирует она сама
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	g.cf.Bind(fs)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() { c.usage(os.Stdout, fs) }
+	return c.run(g, fs, []string{"-h"})
+}
+
+func lookup(args []string) (*command, []string) {
+	if len(args) > 1 {
+		if c := find(args[0] + " " + args[1]); c != nil {
+			return c, args[2:]
+		}
+	}
+	if c := find(args[0]); c != nil {
+		return c, args[1:]
+	}
+	return nil, nil
+}
+
+func find(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// unknown: для группы (history, stores, ...) — её подкоманды, иначе ошибка.
+func unknown(w io.Writer, name string) int {
+	var sub []*command
+	for _, c := range commands {
+		if strings.HasPrefix(c.name, name+" ") {
+			sub = append(sub, c)
+		}
+	}
+	if len(sub) == 0 {
+		fmt.Fprintf(os.Stderr, "kuperparser: unknown command %q (see kuperparser help)\n", name)
+		return exitUsage
+	}
+	fmt.Fprintf(w, "usage: kuperparser %s <command>\n\ncommands:\n", name)
+	writeCommands(w, sub)
+	return exitUsage
+}
+
+// parse разбирает флаги команды; ok=false — выйти с code (-h — 0).
+func parse(fs *flag.FlagSet, args []string) (code int, ok bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// setup загружает конфиг и настраивает логгер после разбора флагов.
+// nil-конфиг — команду выполнять не нужно, выход с code
+// (-print-config или ошибка загрузки).
+func (g *globals) setup() (*config.Config, *slog.Logger, int) {
+	cfg, err := g.cf.Load()
+	if err != nil {
+		slog.Error("load config failed", "err", err)
+		return nil, nil, exitFailure
+	}
+	if g.cf.PrintConfig {
+		if err := config.Print(os.Stdout, cfg); err != nil {
+			slog.Error("print config failed", "err", err)
+			return nil, nil, exitFailure
+		}
+		return nil, nil, exitOK
+	}
+
+	log := logger.New(logger.Options{
+		Level:     cfg.Log.Level,
+		Format:    cfg.Log.Format,
+		AddSource: cfg.Log.AddSource,
+		Env:       cfg.Env,
+		LevelVar:  g.level,
+	})
+	slog.SetDefault(log)
+	return cfg, log, exitOK
+}
+
+func writeUsage(w io.Writer) {
+	fmt.Fprint(w, `usage: kuperparser [global flags] <command> [flags] [args]
+
+commands:
+`)
+	writeCommands(w, commands)
+	fmt.Fprintf(w, `
+global flags (before or after the command):
+  -config path       config.yaml (env %[1]s_CONFIG, default %[2]s)
+  -env name          профиль конфига (env %[1]s_ENV)
+  -print-config      напечатать итоговый конфиг и выйти
+  -<section.key> v   любое поле конфига, например -http.retries 5
+                     (полный список: kuperparser help flags)
+
+exit codes: %[3]d — успех, %[4]d — ошибка, %[5]d — неверные флаги или аргументы,
+%[6]d — остановлено по Ctrl-C (частичный результат сохранён)
+
+kuperparser help <command> — флаги и подробности команды
+`, config.EnvPrefix, config.DefaultPath, exitOK, exitFailure, exitUsage, bootstrap.ExitInterrupted)
+}
+
+func writeCommands(w io.Writer, list []*command) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, c := range list {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.name, c.summary)
+	}
+	_ = tw.Flush()
+}
+
+// usage — справка команды: только её флаги, глобальные — одной строкой.
+func (c *command) usage(w io.Writer, fs *flag.FlagSet) {
+	synopsis := "kuperparser " + c.name + " [flags]"
+	if c.args != "" {
+		synopsis += " " + c.args
+	}
+	fmt.Fprintf(w, "usage: %s\n\n%s\n", synopsis, c.summary)
+	if c.help != "" {
+		fmt.Fprintf(w, "\n%s\n", c.help)
+	}
+
+	own := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	own.SetOutput(w)
+	fs.VisitAll(func(f *flag.Flag) {
+		if globalFlags[f.Name] {
+			return
+		}
+		own.Var(f.Value, f.Name, f.Usage)
+		own.Lookup(f.Name).DefValue = f.DefValue
+	})
+	hasOwn := false
+	own.VisitAll(func(*flag.Flag) { hasOwn = true })
+	if hasOwn {
+		fmt.Fprintln(w, "\nflags:")
+		own.PrintDefaults()
+	}
+	fmt.Fprintln(w, "\nglobal flags: -config, -env, -print-config, -<section.key> (kuperparser help flags)")
+}
+
+// globalFlags — имена глобальных флагов, в справке команды они не повторяются.
+var globalFlags = func() map[string]bool {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	config.BindFlags(fs)
+	names := make(map[string]bool)
+	fs.VisitAll(func(f *flag.Flag) { names[f.Name] = true })
+	return names
+}()
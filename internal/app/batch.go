@@ -0,0 +1,194 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"kuperparser/internal/apis/kuper"
+	"kuperparser/internal/apis/kuper/usecases"
+	"kuperparser/internal/batch"
+	"kuperparser/internal/bootstrap"
+	"kuperparser/internal/checkpoint"
+	"kuperparser/internal/config"
+	"kuperparser/internal/repository/sinks"
+)
+
+const batchHelp = `JOBS — yaml (jobs: [{store: 86, category: 68499}, {store: 86, category: all}])
+       или csv (store,category); category all — весь каталог магазина
+-out — путь выгрузки задания с {store}, {category}, {date}; формат —
+       по расширению или -format
+-resume — вести чекпоинт и продолжить прерванный запуск: готовые задания
+       пропускаются, начатые — с последней сохранённой страницы
+Код выхода 1, если упало хоть одно задание.`
+
+const defaultBatchOutput = "./output/batch/{store}/{category}.json"
+
+// runBatch выполняет задания из файла пулом воркеров с общим транспортом;
+// каждое задание пишет свой файл. Код 1 — если упало хоть одно задание.
+func runBatch(g *globals, fs *flag.FlagSet, args []string) int {
+	workers := fs.Int("workers", 4, "parallel jobs")
+	out := fs.String("out", "", "output path template (default: output from jobs file or "+defaultBatchOutput+")")
+	g.cf.Alias(fs, "format", "cli.format", "output format of every job (default: by -out extension)")
+	resume := fs.Bool("resume", false, "skip jobs finished by the interrupted run and continue started ones from the checkpoint")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 || *workers <= 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	cfg, log, code := g.setup()
+	if cfg == nil {
+		return code
+	}
+
+	file, err := batch.Load(fs.Arg(0))
+	if err != nil {
+		log.Error("load jobs failed", "err", err)
+		return exitFailure
+	}
+	template := *out
+	if template == "" {
+		template = file.Output
+	}
+	if template == "" {
+		template = defaultBatchOutput
+	}
+	now := time.Now()
+	if err := batch.CheckOutputs(template, file.Jobs, now); err != nil {
+		log.Error("invalid output template", "err", err, "output", template)
+		return exitUsage
+	}
+
+	// выход заданий category all проверяется до первого запроса
+	for _, j := range file.Jobs {
+		if !j.All {
+			continue
+		}
+		cfg := jobConfig(cfg, j, batch.Render(template, j, now))
+		sink, err := sinks.FromConfig(&cfg, log)
+		if err != nil {
+			log.Error("init output failed", "err", err)
+			return exitFailure
+		}
+		if !catalogSupported(sink, log) {
+			return exitUsage
+		}
+		break
+	}
+
+	cp, err := openCheckpoint(cfg, log, fs.Arg(0), map[string]string{"mode": "batch", "output": template}, *resume)
+	if err != nil {
+		log.Error("open checkpoint failed", "err", err)
+		return exitFailure
+	}
+	completed := make(map[string]checkpoint.Completed)
+	for _, j := range file.Jobs {
+		if c, ok := cp.Completed(j.Key()); ok {
+			completed[j.Key()] = c
+		}
+	}
+
+	// один транспорт на все задания: лимиты, прокси и ретраи общие
+	kuperSvc, err := newKuper(cfg, log, max(5, *workers))
+	if err != nil {
+		log.Error("build transport failed", "err", err)
+		return exitFailure
+	}
+	usecase := newUsecase(cfg, log, kuperSvc)
+
+	// Ctrl-C: новые задания не начинаются, начатые сохраняют загруженное
+	ctx, stopSignals := bootstrap.SignalContext(context.Background(), log)
+	defer stopSignals()
+
+	log.Info("batch started", "jobs", len(file.Jobs), "resumed", len(completed), "workers", *workers, "output", template)
+
+	results := batch.Run(ctx, file.Jobs, *workers, func(ctx context.Context, j batch.Job) (int, error) {
+		if c, ok := completed[j.Key()]; ok {
+			return c.Count, nil
+		}
+		jlog := log.With("store_id", j.StoreID, "category", j.Category())
+		path := batch.Render(template, j, now)
+		n, err := runBatchJob(ctx, cfg, jlog, kuperSvc, usecase.WithProgress(cp.Progress(j.Key())), j, path)
+		if err != nil {
+			jlog.Error("job failed", "err", err, "count", n)
+			return n, err
+		}
+		jlog.Info("job done", "count", n)
+		if err := cp.Complete(j.Key(), n, path); err != nil {
+			jlog.Warn("save checkpoint failed", "err", err)
+		}
+		return n, nil
+	})
+	for i := range results {
+		results[i].Output = batch.Render(template, results[i].Job, now)
+		// выгрузка прошлого запуска — по её пути, {date} мог смениться
+		if c, ok := completed[results[i].Job.Key()]; ok {
+			results[i].Output, results[i].Resumed = c.Output, true
+		}
+	}
+	if batch.Failed(results) > 0 {
+		flushCheckpoint(cp, log)
+	} else {
+		removeCheckpoint(cp, log)
+	}
+
+	if err := batch.WriteSummary(os.Stdout, results); err != nil {
+		log.Error("write summary failed", "err", err)
+		return exitFailure
+	}
+	switch {
+	case bootstrap.Interrupted(ctx):
+		return bootstrap.ExitInterrupted
+	case batch.Failed(results) > 0:
+		return exitFailure
+	}
+	return exitOK
+}
+
+// runBatchJob — одно задание: конфиг CLI с магазином, категорией и выходом
+// задания (cli.sinks не наследуются, history.dir — да).
+func runBatchJob(ctx context.Context, base *config.Config, log *slog.Logger, svc kuper.KuperService, usecase *usecases.CategoryProductsService, j batch.Job, path string) (int, error) {
+	cfg := jobConfig(base, j, path)
+	sink, err := sinks.FromConfig(&cfg, log)
+	if err != nil {
+		return 0, fmt.Errorf("init output: %w", err)
+	}
+
+	ctx, cancel := jobContext(ctx, &cfg)
+	defer cancel()
+
+	storeMeta := fetchStoreMeta(ctx, svc, j.StoreID, log)
+
+	if j.All {
+		res, err := crawlCatalog(ctx, sink, usecase, j.StoreID, storeMeta)
+		if err != nil {
+			return res.Count, err
+		}
+		if len(res.FailedDepartments) > 0 {
+			return res.Count, fmt.Errorf("%d of %d departments failed", len(res.FailedDepartments), res.Departments)
+		}
+		return res.Count, nil
+	}
+
+	if needsCategoryTree(&cfg) {
+		attachCategoryTree(ctx, sink, svc, j.StoreID, log)
+	}
+	res, err := crawlCategory(ctx, sink, usecase, j.StoreID, j.CategoryID, storeMeta, nil)
+	return res.Count, err
+}
+
+// jobConfig — конфиг CLI задания: его магазин, категория и выход.
+func jobConfig(base *config.Config, j batch.Job, path string) config.Config {
+	cfg := *base
+	cfg.Kuper.StoreID = j.StoreID
+	cfg.CLI.CategoryID = j.CategoryID
+	cfg.CLI.OutputFile = path
+	cfg.CLI.Sinks = nil
+	return cfg
+}
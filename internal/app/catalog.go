@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"kuperparser/internal/apis/kuper/usecases"
+	"kuperparser/internal/bootstrap"
+	"kuperparser/internal/checkpoint"
+	"kuperparser/internal/config"
+	"kuperparser/internal/repository"
+)
+
+// catalogSupported — выход умеет сохранять полный каталог; иначе -all
+// завершается ошибкой использования до первого запроса.
+func catalogSupported(sink repository.Sink, log *slog.Logger) bool {
+	if repository.SupportsCatalog(sink) {
+		return true
+	}
+	log.Error("output format does not support full catalog (use json, ndjson, csv, xlsx or webhook)")
+	return false
+}
+
+// runCatalog — режим -all: весь каталог магазина одним результатом.
+func runCatalog(ctx context.Context, cfg *config.Config, log *slog.Logger, sink repository.Sink, svc *usecases.CategoryProductsService, store *repository.StoreMeta, cp *checkpoint.Checkpoint) int {
+	res, err := crawlCatalog(ctx, sink, svc, cfg.Kuper.StoreID, store)
+	if err != nil {
+		flushCheckpoint(cp, log)
+		switch {
+		case interrupted(ctx):
+			log.Warn("crawl catalog interrupted", "err", err, "count", res.Count)
+			return bootstrap.ExitInterrupted
+		case errors.Is(err, repository.ErrUnsupported):
+			log.Error("output format does not support full catalog (use json, ndjson, csv, xlsx or webhook)", "err", err)
+		default:
+			log.Error("crawl catalog failed", "err", err, "count", res.Count, "incomplete", res.Incomplete)
+		}
+		return exitFailure
+	}
+
+	log.Info("done",
+		"env", cfg.Env,
+		"store_id", cfg.Kuper.StoreID,
+		"departments", res.Departments,
+		"failed_departments", len(res.FailedDepartments),
+		"count", res.Count,
+	)
+	if len(res.FailedDepartments) > 0 {
+		// пройденные разделы в чекпоинте: -resume догрузит только упавшие
+		flushCheckpoint(cp, log)
+		return exitFailure
+	}
+	removeCheckpoint(cp, log)
+	return exitOK
+}
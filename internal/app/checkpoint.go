@@ -0,0 +1,54 @@
+package app
+
+import (
+	"log/slog"
+	"strconv"
+
+	"kuperparser/internal/checkpoint"
+	"kuperparser/internal/config"
+)
+
+// openCheckpoint открывает чекпоинт выгрузки: cli.checkpoint_file, иначе
+// <fallback>.checkpoint.json. params — режим и его параметры; к ним
+// добавляются настройки, от которых зависят страницы. Чекпоинт включается
+// только cli.checkpoint_file или -resume, иначе nil (прогресс не пишется).
+func openCheckpoint(cfg *config.Config, log *slog.Logger, fallback string, params map[string]string, resume bool) (*checkpoint.Checkpoint, error) {
+	path := cfg.CLI.CheckpointFile
+	if path == "" && !resume {
+		return nil, nil
+	}
+	if path == "" {
+		if fallback == "" {
+			fallback = "kuperparser"
+		}
+		path = fallback + ".checkpoint.json"
+	}
+
+	params["kuper.base_url"] = cfg.Kuper.BaseURL
+	params["pagination.per_page"] = strconv.Itoa(cfg.Pagination.PerPage)
+	params["pagination.offers_limit"] = strconv.Itoa(cfg.Pagination.OffersLimit)
+	params["pagination.max_pages"] = strconv.Itoa(cfg.Pagination.MaxPages)
+	return checkpoint.Open(path, params, resume, log)
+}
+
+// flushCheckpoint сохраняет прогресс после сбоя и подсказывает, как продолжить.
+func flushCheckpoint(cp *checkpoint.Checkpoint, log *slog.Logger) {
+	if cp == nil {
+		return
+	}
+	if err := cp.Flush(); err != nil {
+		log.Error("save checkpoint failed", "err", err)
+		return
+	}
+	log.Info("progress saved, rerun with -resume to continue", "checkpoint", cp.Path())
+}
+
+// removeCheckpoint — выгрузка завершена, продолжать нечего.
+func removeCheckpoint(cp *checkpoint.Checkpoint, log *slog.Logger) {
+	if cp == nil {
+		return
+	}
+	if err := cp.Remove(); err != nil {
+		log.Warn("remove checkpoint failed", "err", err)
+	}
+}
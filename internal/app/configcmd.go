@@ -0,0 +1,63 @@
+package app
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"kuperparser/internal/config"
+)
+
+// runConfigValidate: kuperparser config validate [-all]
+// Код выхода 0 — конфиг корректен, 1 — есть проблемы (для CI).
+// Конфиг не загружается заранее: проверка работает и с невалидным.
+func runConfigValidate(g *globals, fs *flag.FlagSet, args []string) int {
+	all := fs.Bool("all", false, "validate every profile in the file, not only the selected one")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	cf := g.cf
+
+	if !*all {
+		return validateProfile(cf)
+	}
+
+	names, err := config.Profiles(cf.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cf.Path, err)
+		return exitFailure
+	}
+	code := exitOK
+	for _, name := range names {
+		pf := *cf
+		pf.Env = name
+		if validateProfile(&pf) != 0 {
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+func validateProfile(cf *config.Flags) int {
+	cfg, err := cf.Load()
+	if err != nil {
+		var ve *config.ValidationError
+		if errors.As(err, &ve) {
+			for _, p := range ve.Problems {
+				fmt.Printf("%s: %s\n", ve.File, p)
+			}
+			fmt.Printf("%s: %d problem(s) (env=%s)\n", cf.Path, len(ve.Problems), cf.Profile())
+			return exitFailure
+		}
+		fmt.Fprintf(os.Stderr, "%s: %v (env=%s)\n", cf.Path, err, cf.Profile())
+		return exitFailure
+	}
+
+	fmt.Printf("%s: ok (env=%s)\n", cf.Path, cfg.Env)
+	return exitOK
+}
@@ -0,0 +1,294 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"kuperparser/internal/alerts"
+	"kuperparser/internal/apis/kuper/usecases"
+	"kuperparser/internal/batch"
+	"kuperparser/internal/bootstrap"
+	"kuperparser/internal/config"
+	"kuperparser/internal/domain/models"
+	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/sinks"
+)
+
+const crawlHelp = `Товары идут в выходы cli (output_file или cli.sinks) по мере загрузки
+страниц. -all — весь каталог магазина одним результатом (json, ndjson, csv,
+xlsx, webhook). С -resume или cli.checkpoint_file прогресс пишется в чекпоинт:
+после сбоя или Ctrl-C -resume продолжит с последней сохранённой страницы.`
+
+type crawlFlags struct {
+	all    *bool
+	resume *bool
+}
+
+// bindCrawlFlags — флаги crawl; -store, -category, -out, -format — синонимы полей конфига.
+func bindCrawlFlags(g *globals, fs *flag.FlagSet) crawlFlags {
+	g.cf.Alias(fs, "store", "kuper.store_id", "store id (kuper.store_id)")
+	g.cf.Alias(fs, "category", "cli.category_id", "category id (cli.category_id)")
+	g.cf.Alias(fs, "out", "cli.output_file", "output file (cli.output_file)")
+	g.cf.Alias(fs, "format", "cli.format", "output format: json|ndjson|csv|tsv|xlsx|yml|sql (default: by -out extension)")
+	return crawlFlags{
+		all:    fs.Bool("all", false, "crawl the whole store catalog into one output (json, ndjson, csv, xlsx, webhook)"),
+		resume: fs.Bool("resume", false, "keep a checkpoint (cli.checkpoint_file) and continue an interrupted crawl from it"),
+	}
+}
+
+func runCrawl(g *globals, fs *flag.FlagSet, args []string) int {
+	f := bindCrawlFlags(g, fs)
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	return crawl(g, f)
+}
+
+func crawl(g *globals, f crawlFlags) int {
+	cfg, log, code := g.setup()
+	if cfg == nil {
+		return code
+	}
+
+	if cfg.Kuper.StoreID <= 0 {
+		log.Error("store_id must be > 0 (set in config.yaml or via -store)")
+		return exitUsage
+	}
+	if !*f.all && cfg.CLI.CategoryID <= 0 {
+		log.Error("category_id must be > 0 (set in config.yaml or via -category)")
+		return exitUsage
+	}
+	if cfg.CLI.OutputFile == "" && len(cfg.CLI.Sinks) == 0 {
+		log.Error("output_file must not be empty (set in config.yaml or via -out)")
+		return exitUsage
+	}
+
+	sink, err := sinks.FromConfig(cfg, log)
+	if err != nil {
+		log.Error("init output failed", "err", err)
+		return exitFailure
+	}
+	if *f.all && !catalogSupported(sink, log) {
+		return exitUsage
+	}
+
+	alerter, err := alerts.FromConfig(cfg, log)
+	if err != nil {
+		log.Error("init alerts failed", "err", err)
+		return exitFailure
+	}
+	if *f.all && alerter != nil {
+		log.Info("alerts are evaluated per category, skipped for -all")
+		alerter = nil
+	}
+	var prev *repository.CategoryResult
+	if alerter != nil {
+		prev = previousSnapshot(cfg, log)
+	}
+
+	kuperSvc, err := newKuper(cfg, log, 5)
+	if err != nil {
+		log.Error("build transport failed", "err", err)
+		return exitFailure
+	}
+	usecase := newUsecase(cfg, log, kuperSvc)
+
+	// прогресс по страницам: после сбоя -resume продолжит с места остановки
+	job := batch.Job{StoreID: cfg.Kuper.StoreID, CategoryID: cfg.CLI.CategoryID, All: *f.all}
+	params := map[string]string{"mode": "category", "store_id": strconv.Itoa(job.StoreID), "category_id": strconv.Itoa(job.CategoryID)}
+	if *f.all {
+		params = map[string]string{"mode": "all", "store_id": strconv.Itoa(job.StoreID)}
+	}
+	cp, err := openCheckpoint(cfg, log, cfg.CLI.OutputFile, params, *f.resume)
+	if err != nil {
+		log.Error("open checkpoint failed", "err", err)
+		return exitFailure
+	}
+	usecase = usecase.WithProgress(cp.Progress(job.Key()))
+
+	// Ctrl-C: новые запросы не уходят, начатые дожидаются, загруженное сохраняется
+	sigCtx, stopSignals := bootstrap.SignalContext(context.Background(), log)
+	defer stopSignals()
+
+	// дедлайн всей выгрузки; таймауты запроса и страницы — в транспорте и usecase
+	ctx, cancel := jobContext(sigCtx, cfg)
+	defer cancel()
+
+	storeMeta := fetchStoreMeta(ctx, kuperSvc, cfg.Kuper.StoreID, log)
+
+	if *f.all {
+		return runCatalog(ctx, cfg, log, sink, usecase, storeMeta, cp)
+	}
+
+	if needsCategoryTree(cfg) {
+		attachCategoryTree(ctx, sink, kuperSvc, cfg.Kuper.StoreID, log)
+	}
+
+	// товары уходят в выходы по мере загрузки страниц; форматы, которые
+	// пишутся только целиком, копят их сами. Для правил оповещений нужен
+	// весь список товаров
+	var collected []models.Product
+	summary, err := crawlCategory(ctx, sink, usecase, cfg.Kuper.StoreID, cfg.CLI.CategoryID, storeMeta, func(page []models.Product) error {
+		if alerter != nil {
+			collected = append(collected, page...)
+		}
+		return nil
+	})
+	if err != nil {
+		flushCheckpoint(cp, log)
+		if interrupted(ctx) {
+			log.Warn("crawl category interrupted", "err", err, "count", summary.Count)
+			return bootstrap.ExitInterrupted
+		}
+		log.Error("crawl category failed", "err", err, "count", summary.Count, "incomplete", summary.Incomplete)
+		return exitFailure
+	}
+	removeCheckpoint(cp, log)
+
+	if alerter != nil {
+		summary.Products = collected
+		// выгрузка уже сохранена — сбой оповещений её не отменяет
+		if _, err := alerter.Run(ctx, prev, summary, cfg.Kuper.StoreID); err != nil {
+			log.Error("alerts failed", "err", err)
+		}
+	}
+
+	log.Info("done",
+		"env", cfg.Env,
+		"store_id", cfg.Kuper.StoreID,
+		"category_id", cfg.CLI.CategoryID,
+		"slug", summary.Category.Slug,
+		"count", summary.Count,
+		"outputs", len(sinks.Specs(cfg)),
+	)
+	return exitOK
+}
+
+// Остановка по сигналу или дедлайну задания: то, что уже загружено,
+// сохраняется с incomplete и возвращается ошибка с причиной (ErrInterrupted,
+// errJobDeadline) и местом остановки. Если не загружено ничего, выход
+// не трогается — прежняя выгрузка остаётся на месте. Запись идёт с
+// контекстом без отмены, иначе остановка оборвала бы и её.
+
+// errJobDeadline — причина отмены контекста задания по cli.job_timeout_seconds.
+var errJobDeadline = errors.New("job deadline exceeded")
+
+// jobContext — контекст одной выгрузки с дедлайном cli.job_timeout_seconds
+// (0 — без дедлайна); отдельно от таймаута HTTP-запроса и страницы.
+// Сигнал (отмена sig из SignalContext) запросы не обрывает: он только не
+// даёт начать следующую страницу (usecases.WithStop), начатая дожидается.
+// Дедлайн и отмена родителя sig доходят до транспорта как обычно.
+func jobContext(sig context.Context, cfg *config.Config) (context.Context, context.CancelFunc) {
+	parent := context.WithoutCancel(sig)
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if cfg.CLI.JobTimeoutSeconds <= 0 {
+		ctx, cancel = context.WithCancel(parent)
+	} else {
+		ctx, cancel = context.WithTimeoutCause(parent, time.Duration(cfg.CLI.JobTimeoutSeconds)*time.Second, errJobDeadline)
+	}
+	return usecases.WithStop(ctx, sig), cancel
+}
+
+// stopCause — почему выгрузку остановили досрочно: сигнал или дедлайн
+// задания; nil — не останавливали (ошибка сама по себе).
+func stopCause(ctx context.Context) error {
+	cause := usecases.StopCause(ctx)
+	if errors.Is(cause, bootstrap.ErrInterrupted) || errors.Is(cause, errJobDeadline) {
+		return cause
+	}
+	return nil
+}
+
+// interrupted — выгрузку остановил сигнал (а не дедлайн или ошибка).
+func interrupted(ctx context.Context) bool {
+	return errors.Is(usecases.StopCause(ctx), bootstrap.ErrInterrupted)
+}
+
+// crawlCategory выгружает категорию в sink постранично; onPage (если задан)
+// видит каждую страницу до записи.
+func crawlCategory(ctx context.Context, sink repository.Sink, svc *usecases.CategoryProductsService, storeID, categoryID int, store *repository.StoreMeta, onPage usecases.PageFunc) (repository.CategoryResult, error) {
+	saveCtx := context.WithoutCancel(ctx)
+
+	res := repository.CategoryResult{Store: store, Category: &repository.CategoryMeta{ID: categoryID}}
+	st, err := sink.OpenCategory(saveCtx)
+	if err != nil {
+		return res, fmt.Errorf("open output: %w", err)
+	}
+
+	slug, count, err := svc.StreamByCategoryID(ctx, storeID, categoryID, func(page []models.Product) error {
+		if onPage != nil {
+			if err := onPage(page); err != nil {
+				return err
+			}
+		}
+		return st.WriteProducts(saveCtx, page)
+	})
+	res.FetchedAt = time.Now().UTC().Format(time.RFC3339)
+	res.Category.Slug = slug
+	res.Count = count
+	cause := stopCause(ctx)
+	if err != nil {
+		if cause == nil || count == 0 {
+			st.Abort()
+			return res, fmt.Errorf("parse category: %w", err)
+		}
+		res.Incomplete = true
+	}
+
+	if serr := st.Close(res); serr != nil {
+		return res, fmt.Errorf("save output: %w", serr)
+	}
+	if res.Incomplete {
+		return res, errPartial(cause, err, res.Count)
+	}
+	return res, nil
+}
+
+// crawlCatalog выгружает каталог магазина в sink по разделам; упавшие
+// разделы не мешают сохранению, но видны в FailedDepartments.
+func crawlCatalog(ctx context.Context, sink repository.Sink, svc *usecases.CategoryProductsService, storeID int, store *repository.StoreMeta) (repository.CatalogResult, error) {
+	saveCtx := context.WithoutCancel(ctx)
+
+	st, err := repository.OpenCatalog(saveCtx, sink)
+	if err != nil {
+		return repository.CatalogResult{Store: store}, fmt.Errorf("open output: %w", err)
+	}
+
+	res, err := svc.Catalog(ctx, storeID, func(products []repository.CatalogProduct) error {
+		return st.WriteProducts(saveCtx, products)
+	})
+	res.FetchedAt = time.Now().UTC().Format(time.RFC3339)
+	res.Store = store
+	cause := stopCause(ctx)
+	if err != nil {
+		if cause == nil || res.Count == 0 {
+			st.Abort()
+			return res, err
+		}
+		res.Incomplete = true
+	}
+
+	if serr := st.Close(res); serr != nil {
+		return res, fmt.Errorf("save output: %w", serr)
+	}
+	if res.Incomplete {
+		return res, errPartial(cause, err, res.Count)
+	}
+	return res, nil
+}
+
+// errPartial: cause — для errors.Is, err — где остановились.
+func errPartial(cause, err error, count int) error {
+	return fmt.Errorf("%w (%v), partial result saved (%d products)", cause, err, count)
+}
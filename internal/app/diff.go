@@ -0,0 +1,60 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"kuperparser/internal/diff"
+	"kuperparser/internal/repository/reader"
+)
+
+const diffHelp = `OLD, NEW — json/ndjson (в том числе .gz) любой поддерживаемой версии схемы,
+оба — выгрузки категории или оба — списки магазинов.
+Код выхода как у diff(1): с -exit-code 1 — есть изменения, 2 — ошибка.`
+
+// runDiff: kuperparser diff [-format text|json|csv] [-exit-code] old new
+// Конфиг не нужен.
+func runDiff(_ *globals, fs *flag.FlagSet, args []string) int {
+	format := fs.String("format", "text", "report format: "+strings.Join(diff.Formats, "|"))
+	exitCode := fs.Bool("exit-code", false, "exit with 1 if there are changes")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	old, err := reader.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	cur, err := reader.Load(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	var rep diff.Report
+	switch {
+	case old.Category != nil && cur.Category != nil:
+		rep = diff.Categories(*old.Category, *cur.Category)
+	case old.Stores != nil && cur.Stores != nil:
+		rep = diff.Stores(*old.Stores, *cur.Stores)
+	default:
+		fmt.Fprintln(os.Stderr, "diff: files contain different result kinds (category vs stores)")
+		return exitUsage
+	}
+
+	if err := diff.Write(os.Stdout, *format, rep); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if *exitCode && !rep.Empty() {
+		return exitFailure
+	}
+	return exitOK
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"kuperparser/internal/config"
+	"kuperparser/internal/http-server/query"
+	"kuperparser/internal/repository/history"
+)
+
+const historyHelp = `История цен из history.dir.
+T — RFC3339 или YYYY-MM-DD (для -to/-at — конец дня); -store 0 — все магазины (prices)`
+
+// historyStore — настройка команды history: конфиг, логгер и история цен;
+// nil — выйти с code.
+func historyStore(g *globals) (*config.Config, *history.Store, *slog.Logger, int) {
+	cfg, log, code := g.setup()
+	if cfg == nil {
+		return nil, nil, nil, code
+	}
+	if cfg.History.Dir == "" {
+		log.Error("history.dir is not set (config.yaml or -history.dir)")
+		return nil, nil, nil, exitFailure
+	}
+	return cfg, history.New(cfg.History.Dir, log), log, exitOK
+}
+
+func runHistoryPrices(g *globals, fs *flag.FlagSet, args []string) int {
+	url := fs.String("url", "", "product url")
+	g.cf.Alias(fs, "store", "kuper.store_id", "store id (0 = all stores, default kuper.store_id)")
+	fromRaw := fs.String("from", "", "from time (RFC3339 or YYYY-MM-DD)")
+	toRaw := fs.String("to", "", "to time (RFC3339 or YYYY-MM-DD)")
+	asJSON := fs.Bool("json", false, "print json instead of table")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if *url == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	cfg, store, log, code := historyStore(g)
+	if cfg == nil {
+		return code
+	}
+
+	from, err := parseOptionalTime(*fromRaw, false)
+	if err != nil {
+		log.Error("bad -from", "err", err)
+		return exitUsage
+	}
+	to, err := parseOptionalTime(*toRaw, true)
+	if err != nil {
+		log.Error("bad -to", "err", err)
+		return exitUsage
+	}
+
+	points, err := store.Timeline(cfg.Kuper.StoreID, *url, from, to)
+	if err != nil {
+		log.Error("history query failed", "err", err)
+		return exitFailure
+	}
+
+	if *asJSON {
+		return printJSON(points)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FETCHED_AT\tSTORE\tCATEGORY\tPRICE\tNAME")
+	for _, p := range points {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\n", p.FetchedAt, p.StoreID, p.CategoryID, p.Price, p.Name)
+	}
+	_ = tw.Flush()
+	return exitOK
+}
+
+func runHistorySnapshot(g *globals, fs *flag.FlagSet, args []string) int {
+	g.cf.Alias(fs, "category", "cli.category_id", "category id (default cli.category_id)")
+	g.cf.Alias(fs, "store", "kuper.store_id", "store id (default kuper.store_id)")
+	atRaw := fs.String("at", "", "point in time (RFC3339 or YYYY-MM-DD), default now")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	cfg, store, log, code := historyStore(g)
+	if cfg == nil {
+		return code
+	}
+	if cfg.CLI.CategoryID <= 0 || cfg.Kuper.StoreID <= 0 {
+		log.Error("store and category must be > 0 (-store, -category or config)")
+		return exitUsage
+	}
+
+	at := time.Now()
+	if *atRaw != "" {
+		t, err := query.ParseTime(*atRaw, true)
+		if err != nil {
+			log.Error("bad -at", "err", err)
+			return exitUsage
+		}
+		at = t
+	}
+
+	res, err := store.Snapshot(cfg.Kuper.StoreID, cfg.CLI.CategoryID, at)
+	if err != nil {
+		log.Error("history query failed", "err", err)
+		return exitFailure
+	}
+	return printJSON(res)
+}
+
+func parseOptionalTime(raw string, endOfDay bool) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	return query.ParseTime(raw, endOfDay)
+}
+
+func printJSON(v any) int {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	return exitOK
+}
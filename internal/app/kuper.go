@@ -0,0 +1,200 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"kuperparser/internal/apis/kuper/endpoints"
+	"kuperparser/internal/bootstrap"
+	"kuperparser/internal/config"
+	"kuperparser/internal/http-server/handlers/categories"
+	"kuperparser/internal/repository"
+)
+
+// Разовые запросы к kuper с выводом в stdout: таблица или -json в том же
+// виде, что отдаёт HTTP API. Ничего не сохраняют.
+
+// queryContext — Ctrl-C и дедлайн cli.job_timeout_seconds для разового
+// запроса; по Ctrl-C начатая страница товаров дожидается, как в crawl.
+func queryContext(cfg *config.Config, log *slog.Logger) (context.Context, context.CancelFunc) {
+	sigCtx, stopSignals := bootstrap.SignalContext(context.Background(), log)
+	ctx, cancel := jobContext(sigCtx, cfg)
+	return ctx, func() {
+		cancel()
+		stopSignals()
+	}
+}
+
+// queryFailed — код выхода упавшего запроса: 130 после Ctrl-C, иначе 1.
+func queryFailed(ctx context.Context, log *slog.Logger, msg string, err error, args ...any) int {
+	if interrupted(ctx) {
+		log.Warn(msg+": interrupted", append([]any{"err", err}, args...)...)
+		return bootstrap.ExitInterrupted
+	}
+	var apiErr *endpoints.APIError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		msg += ": not found"
+	}
+	log.Error(msg, append([]any{"err", err}, args...)...)
+	return exitFailure
+}
+
+func runCategories(g *globals, fs *flag.FlagSet, args []string) int {
+	g.cf.Alias(fs, "store", "kuper.store_id", "store id (kuper.store_id)")
+	asJSON := fs.Bool("json", false, "print json (as GET /categories) instead of table")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	cfg, log, code := g.setup()
+	if cfg == nil {
+		return code
+	}
+	if cfg.Kuper.StoreID <= 0 {
+		log.Error("store_id must be > 0 (set in config.yaml or via -store)")
+		return exitUsage
+	}
+
+	svc, err := newKuper(cfg, log, 1)
+	if err != nil {
+		log.Error("build transport failed", "err", err)
+		return exitFailure
+	}
+	ctx, cancel := queryContext(cfg, log)
+	defer cancel()
+
+	tree, err := svc.ListCategories(ctx, cfg.Kuper.StoreID)
+	if err != nil {
+		return queryFailed(ctx, log, "list categories failed", err, "store_id", cfg.Kuper.StoreID)
+	}
+	flat := categories.Flatten(tree, true)
+
+	if *asJSON {
+		return printJSON(map[string]any{
+			"store_id":   cfg.Kuper.StoreID,
+			"fetched_at": time.Now().UTC().Format(time.RFC3339),
+			"count":      len(flat),
+			"categories": flat,
+		})
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tPARENT\tPRODUCTS\tSLUG\tNAME")
+	for _, c := range flat {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%s\t%s%s\n", c.ID, c.ParentID, c.ProductsCount, c.Slug, strings.Repeat("  ", c.Level), c.Name)
+	}
+	_ = tw.Flush()
+	return exitOK
+}
+
+func runProducts(g *globals, fs *flag.FlagSet, args []string) int {
+	g.cf.Alias(fs, "store", "kuper.store_id", "store id (kuper.store_id)")
+	g.cf.Alias(fs, "category", "cli.category_id", "category id (cli.category_id)")
+	asJSON := fs.Bool("json", false, "print json (as GET /products) instead of table")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	cfg, log, code := g.setup()
+	if cfg == nil {
+		return code
+	}
+	if cfg.Kuper.StoreID <= 0 || cfg.CLI.CategoryID <= 0 {
+		log.Error("store_id and category_id must be > 0 (set in config.yaml or via -store, -category)")
+		return exitUsage
+	}
+
+	svc, err := newKuper(cfg, log, 5)
+	if err != nil {
+		log.Error("build transport failed", "err", err)
+		return exitFailure
+	}
+	ctx, cancel := queryContext(cfg, log)
+	defer cancel()
+
+	store := fetchStoreMeta(ctx, svc, cfg.Kuper.StoreID, log)
+	products, slug, err := newUsecase(cfg, log, svc).GetByCategoryID(ctx, cfg.Kuper.StoreID, cfg.CLI.CategoryID)
+	if err != nil {
+		return queryFailed(ctx, log, "get products failed", err, "store_id", cfg.Kuper.StoreID, "category_id", cfg.CLI.CategoryID)
+	}
+
+	if *asJSON {
+		return printJSON(repository.CategoryResult{
+			FetchedAt: time.Now().UTC().Format(time.RFC3339),
+			Store:     store,
+			Category:  &repository.CategoryMeta{ID: cfg.CLI.CategoryID, Slug: slug},
+			Products:  products,
+			Count:     len(products),
+		})
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PRICE\tNAME\tURL")
+	for _, p := range products {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Price, p.Name, p.URL)
+	}
+	_ = tw.Flush()
+	fmt.Printf("\n%d products (slug %s)\n", len(products), slug)
+	return exitOK
+}
+
+func runStore(g *globals, fs *flag.FlagSet, args []string) int {
+	g.cf.Alias(fs, "store", "kuper.store_id", "store id (kuper.store_id)")
+	asJSON := fs.Bool("json", false, "print json instead of table")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	cfg, log, code := g.setup()
+	if cfg == nil {
+		return code
+	}
+	if cfg.Kuper.StoreID <= 0 {
+		log.Error("store_id must be > 0 (set in config.yaml or via -store)")
+		return exitUsage
+	}
+
+	svc, err := newKuper(cfg, log, 1)
+	if err != nil {
+		log.Error("build transport failed", "err", err)
+		return exitFailure
+	}
+	ctx, cancel := queryContext(cfg, log)
+	defer cancel()
+
+	info, err := svc.GetStore(ctx, cfg.Kuper.StoreID)
+	if err != nil {
+		return queryFailed(ctx, log, "get store failed", err, "store_id", cfg.Kuper.StoreID)
+	}
+	st := storeMeta(info)
+
+	if *asJSON {
+		return printJSON(st)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tRETAILER\tADDRESS")
+	fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", st.ID, st.Name, st.RetailerName, st.Address)
+	_ = tw.Flush()
+	return exitOK
+}
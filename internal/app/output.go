@@ -0,0 +1,37 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+
+	"kuperparser/internal/apis/kuper"
+	"kuperparser/internal/apis/kuper/usecases"
+	"kuperparser/internal/config"
+	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/sinks"
+)
+
+// needsCategoryTree: дерево категорий нужно только YML-фиду (для parentId).
+func needsCategoryTree(cfg *config.Config) bool {
+	for _, spec := range sinks.Specs(cfg) {
+		if sinks.Type(spec) == "yml" {
+			return true
+		}
+	}
+	return false
+}
+
+// attachCategoryTree отдаёт sink-у дерево категорий магазина.
+// Ошибка не фатальна — фид соберётся и без родителей.
+func attachCategoryTree(ctx context.Context, sink repository.Sink, svc kuper.KuperService, storeID int, log *slog.Logger) {
+	ts, ok := sink.(repository.CategoryTreeSetter)
+	if !ok {
+		return
+	}
+	cats, err := svc.ListCategories(ctx, storeID)
+	if err != nil {
+		log.Warn("list categories failed, yml without parentId (continue)", "err", err, "store_id", storeID)
+		return
+	}
+	ts.SetCategoryTree(usecases.CategoryNodes(cats))
+}
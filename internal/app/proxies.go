@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"kuperparser/internal/client/proxy"
+	"kuperparser/internal/redact"
+)
+
+// runProxiesCheck: kuperparser proxies check [-target URL] [-timeout 10s] [-workers 8]
+func runProxiesCheck(g *globals, fs *flag.FlagSet, args []string) int {
+	target := fs.String("target", "", "url to request through every proxy (default kuper.base_url)")
+	timeout := fs.Duration("timeout", 10*time.Second, "per-proxy request timeout")
+	workers := fs.Int("workers", 8, "concurrent checks")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	cfg, log, code := g.setup()
+	if cfg == nil {
+		return code
+	}
+	if *target == "" {
+		*target = cfg.Kuper.BaseURL
+	}
+
+	ctx := context.Background()
+
+	list := cfg.Proxy.List
+	if cfg.Proxy.Mode == string(proxy.ModeRotation) {
+		// для rotation проверяем тот прокси, который сейчас отдаёт rotation_url
+		ttl := time.Duration(cfg.Proxy.RotationTTLSeconds) * time.Second
+		p, err := proxy.NewRotationProvider(cfg.Proxy.RotationURL, ttl, log).Next(ctx)
+		if err != nil {
+			log.Error("rotation provider failed", "err", redact.Error(err))
+			return exitFailure
+		}
+		list = []string{p}
+	}
+	if len(list) == 0 {
+		log.Error("no proxies configured", "mode", cfg.Proxy.Mode)
+		return exitFailure
+	}
+
+	results := proxy.Check(ctx, list, *target, *timeout, *workers)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROXY\tSTATUS\tLATENCY\tRESULT")
+	ok := 0
+	for _, r := range results {
+		status := "-"
+		if r.Status != 0 {
+			status = strconv.Itoa(r.Status)
+		}
+		result := "ok"
+		switch {
+		case r.Err != nil:
+			result = r.Err.Error()
+		case !r.OK():
+			result = "bad status"
+		default:
+			ok++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Proxy, status, r.Latency.Round(time.Millisecond), result)
+	}
+	_ = tw.Flush()
+
+	fmt.Printf("\n%d/%d proxies ok (target %s)\n", ok, len(results), *target)
+	if ok == 0 {
+		return exitFailure
+	}
+	return exitOK
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"kuperparser/internal/repository/schema"
+)
+
+// runSchema: kuperparser schema [-list] [name]
+// Без имени печатает все схемы одним объектом {name: schema}.
+func runSchema(_ *globals, fs *flag.FlagSet, args []string) int {
+	list := fs.Bool("list", false, "print schema names only")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+
+	if *list {
+		for _, name := range schema.Names() {
+			fmt.Println(name)
+		}
+		return exitOK
+	}
+
+	var v any = schema.All()
+	if fs.NArg() > 0 {
+		s, err := schema.Get(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		v = s
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	return exitOK
+}
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"kuperparser/internal/apis/kuper"
+	"kuperparser/internal/client/proxy"
+	"kuperparser/internal/client/transport"
+
+	"kuperparser/internal/bootstrap"
+	"kuperparser/internal/config"
+	httpserver "kuperparser/internal/http-server"
+	"kuperparser/internal/repository/history"
+
+	"kuperparser/internal/logger"
+)
+
+const serveHelp = `Конфиг перечитывается по SIGHUP (и по изменению файла с -watch-config):
+подменяются транспорт, прокси, уровень логов, ретраи и таймауты; при ошибке
+остаётся прежний. SIGINT/SIGTERM — мягкая остановка, код выхода 0.`
+
+func runServe(g *globals, fs *flag.FlagSet, args []string) int {
+	g.cf.Alias(fs, "host", "server.host", "override host")
+	g.cf.Alias(fs, "port", "server.port", "override port")
+	watch := fs.Duration("watch-config", 0, "reload config when the file changes, poll interval (0 = only on SIGHUP)")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	cfg, log, code := g.setup()
+	if cfg == nil {
+		return code
+	}
+
+	// статистика прокси живёт дольше транспорта: переживает перезагрузки конфига
+	health := bootstrap.NewProxyHealth(cfg)
+
+	deps, tr, err := buildDeps(cfg, log, health)
+	if err != nil {
+		log.Error("build transport failed", "err", err)
+		return exitFailure
+	}
+
+	api := httpserver.New(log)
+	api.RegisterRoutes(deps)
+
+	addr := net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.Port))
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           api.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+
+	var changed <-chan struct{}
+	if *watch > 0 {
+		changed = config.Watch(watchCtx, g.cf.Path, *watch)
+		log.Info("watching config", "path", g.cf.Path, "interval", watch.String())
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Info("api started", "addr", addr)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	for {
+		select {
+		case <-hup:
+			log.Info("SIGHUP received, reloading config", "path", g.cf.Path)
+			cfg, tr = reload(g.cf, cfg, tr, api, health, g.level, log)
+
+		case <-changed:
+			log.Info("config file changed, reloading", "path", g.cf.Path)
+			cfg, tr = reload(g.cf, cfg, tr, api, health, g.level, log)
+
+		case sig := <-stop:
+			log.Info("shutdown signal received", "signal", sig.String())
+
+			// даём запросам завершиться
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			if err := srv.Shutdown(ctx); err != nil {
+				log.Error("graceful shutdown failed", "err", err)
+				_ = srv.Close()
+			}
+			log.Info("server stopped gracefully")
+			return exitOK
+
+		case err := <-errCh:
+
+			if errors.Is(err, http.ErrServerClosed) {
+				log.Info("server closed")
+				return exitOK
+			}
+			log.Error("server stopped with error", "err", err)
+			return exitFailure
+		}
+	}
+}
+
+// buildDeps собирает транспорт, сервис kuper и usecase под конфиг;
+// транспорт возвращается, чтобы закрыть его соединения после замены.
+func buildDeps(cfg *config.Config, log *slog.Logger, health *proxy.Health) (httpserver.Deps, transport.Transport, error) {
+	tr, err := bootstrap.BuildTransportWithHealth(cfg, log, 10, health)
+	if err != nil {
+		return httpserver.Deps{}, nil, err
+	}
+
+	kuperSvc := kuper.New(tr, cfg.Kuper.BaseURL, log)
+	usecase := newUsecase(cfg, log, kuperSvc)
+
+	var hist *history.Store
+	if cfg.History.Dir != "" {
+		hist = history.New(cfg.History.Dir, log)
+	}
+
+	return httpserver.Deps{
+		Categories:     kuperSvc,
+		Products:       usecase,
+		Store:          kuperSvc,
+		History:        hist,
+		DefaultStoreID: cfg.Kuper.StoreID,
+		Timeout:        time.Duration(cfg.HTTP.TimeoutSeconds) * time.Second,
+		ProxyHealth:    health,
+		ProxyMode:      cfg.Proxy.Mode,
+		AdminToken:     cfg.Server.AdminToken,
+	}, tr, nil
+}
+
+// reload перечитывает конфиг и подменяет транспорт, прокси, уровень логов,
+// ретраи и таймауты. Листенер и запросы в полёте не трогаются, у старого
+// транспорта закрываются простаивающие соединения.
+// При любой ошибке остаются старые конфиг и транспорт.
+func reload(cf *config.Flags, cur *config.Config, curTr transport.Transport, api *httpserver.Server, health *proxy.Health, level *slog.LevelVar, log *slog.Logger) (*config.Config, transport.Transport) {
+	next, err := cf.Load()
+	if err != nil {
+		log.Error("reload config failed, keeping current", "err", err)
+		return cur, curTr
+	}
+
+	changes := config.Diff(cur, next)
+	if len(changes) == 0 {
+		log.Info("config reloaded: no changes")
+		return cur, curTr
+	}
+
+	deps, tr, err := buildDeps(next, log, health)
+	if err != nil {
+		log.Error("reload: build transport failed, keeping current", "err", err)
+		return cur, curTr
+	}
+	api.RegisterRoutes(deps)
+	transport.CloseIdleConnections(curTr)
+	// запросы в полёте вернут соединения в старый пул — закрываем и их,
+	// когда истечёт их таймаут
+	time.AfterFunc(time.Duration(cur.HTTP.TimeoutSeconds)*time.Second, func() {
+		transport.CloseIdleConnections(curTr)
+	})
+	level.Set(logger.ParseLevel(next.Log.Level))
+
+	// статистика прокси, которых нет в новом списке, больше не нужна
+	var proxies []string
+	if next.Proxy.Mode == string(proxy.ModeList) {
+		proxies = next.Proxy.List
+	}
+	health.Retain(proxies)
+
+	for _, c := range changes {
+		switch c.Path {
+		case "server.host", "server.port", "log.format", "log.add_source":
+			log.Warn("config changed, requires restart", "field", c.Path, "old", c.Old, "new", c.New)
+		default:
+			log.Info("config changed", "field", c.Path, "old", c.Old, "new", c.New)
+		}
+	}
+	log.Info("config reloaded", "changes", len(changes))
+
+	return next, tr
+}
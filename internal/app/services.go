@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"kuperparser/internal/apis/kuper"
+	"kuperparser/internal/apis/kuper/usecases"
+	"kuperparser/internal/bootstrap"
+	"kuperparser/internal/config"
+	"kuperparser/internal/repository"
+)
+
+// newKuper — клиент kuper поверх транспорта из конфига (прокси, лимиты,
+// ретраи); concurrency — сколько запросов идут параллельно.
+func newKuper(cfg *config.Config, log *slog.Logger, concurrency int) (kuper.KuperService, error) {
+	transport, _, err := bootstrap.BuildTransport(cfg, log, concurrency)
+	if err != nil {
+		return nil, err
+	}
+	return kuper.New(transport, cfg.Kuper.BaseURL, log), nil
+}
+
+// newUsecase — постраничная загрузка товаров с настройками pagination.
+func newUsecase(cfg *config.Config, log *slog.Logger, svc kuper.KuperService) *usecases.CategoryProductsService {
+	return usecases.NewCategoryProductsService(
+		svc,
+		cfg.Kuper.BaseURL,
+		log,
+		cfg.Pagination.PerPage,
+		cfg.Pagination.OffersLimit,
+		cfg.Pagination.MaxPages,
+	).WithPageTimeout(time.Duration(cfg.Pagination.PageTimeoutSeconds) * time.Second)
+}
+
+// fetchStoreMeta — мета магазина для выгрузки; без неё выгрузка продолжается.
+func fetchStoreMeta(ctx context.Context, svc kuper.KuperService, storeID int, log *slog.Logger) *repository.StoreMeta {
+	info, err := svc.GetStore(ctx, storeID)
+	if err != nil {
+		log.Warn("get store info failed (continue)", "err", err, "store_id", storeID)
+		return nil
+	}
+	meta := storeMeta(info)
+	return &meta
+}
+
+func storeMeta(info kuper.StoreInfo) repository.StoreMeta {
+	return repository.StoreMeta{
+		ID:           info.StoreID,
+		Name:         info.StoreName,
+		Address:      info.StoreAddress,
+		RetailerName: info.RetailerName,
+	}
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"kuperparser/internal/apis/kuper/endpoints"
+	"kuperparser/internal/bootstrap"
+	"kuperparser/internal/config"
+	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/sinks"
+)
+
+// тк апишка требует ни сколько адрес, а стор айди,
+// приходится использовать такой костыль на скорую
+// руку, мне просто нужны были хотя бы какие нибудь
+// валидные айдишники для проверки парсинга, в дальнейшем
+// я бы выгружал их более правильным способом, потому что
+// подтягиваются далеко не все айдишники.
+
+const storesScanHelp = `Каждый storeID из диапазона запрашивается отдельно, 404 — магазина нет.
+По Ctrl-C найденное сохраняется с incomplete, код выхода 130.`
+
+func runStoresScan(g *globals, fs *flag.FlagSet, args []string) int {
+	var (
+		from    = fs.Int("from", 1, "start storeID (inclusive)")
+		to      = fs.Int("to", 20000, "end storeID (inclusive)")
+		workers = fs.Int("workers", 40, "concurrent workers (goroutines)")
+		outPath = fs.String("out", "./output/stores.json", "output file, format by extension (.json, .csv, .sql)")
+	)
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 || *from <= 0 || *to <= 0 || *to < *from {
+		fs.Usage()
+		return exitUsage
+	}
+	if *workers <= 0 {
+		*workers = 10
+	}
+
+	cfg, log, code := g.setup()
+	if cfg == nil {
+		return code
+	}
+
+	// transport: можно поставить побольше concurrency
+	kuperSvc, err := newKuper(cfg, log, 50)
+	if err != nil {
+		log.Error("build transport failed", "err", err)
+		return exitFailure
+	}
+
+	ids := make(chan int, 1024)
+	foundCh := make(chan repository.StoreMeta, 1024)
+
+	var scanned uint64
+	var found uint64
+
+	// aggregator
+	var storesMu sync.Mutex
+	stores := make([]repository.StoreMeta, 0, 4096)
+
+	doneAgg := make(chan struct{})
+	go func() {
+		defer close(doneAgg)
+		for s := range foundCh {
+			storesMu.Lock()
+			stores = append(stores, s)
+			storesMu.Unlock()
+		}
+	}()
+
+	// Ctrl-C: новые id не раздаются, начатые запросы дожидаются,
+	// найденное сохраняется с incomplete
+	ctx, stopSignals := bootstrap.SignalContext(context.Background(), log)
+	defer stopSignals()
+
+	// workers
+	var wg sync.WaitGroup
+
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				if ctx.Err() != nil {
+					continue // дочитываем канал, чтобы раздача не зависла
+				}
+				atomic.AddUint64(&scanned, 1)
+
+				info, err := kuperSvc.GetStore(context.WithoutCancel(ctx), id)
+				if err != nil {
+					// 404 — просто нет такого storeID
+					var he *endpoints.APIError
+					if errors.As(err, &he) && he.Status == 404 {
+						continue
+					}
+					// остальное — логируем (но продолжаем)
+					log.Warn("GetStore failed", "store_id", id, "err", err)
+					continue
+				}
+
+				atomic.AddUint64(&found, 1)
+				foundCh <- storeMeta(info)
+			}
+		}()
+	}
+
+	// progress logger
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	go func() {
+		for range ticker.C {
+			s := atomic.LoadUint64(&scanned)
+			f := atomic.LoadUint64(&found)
+			log.Info("scan progress", "scanned", s, "found", f, "from", *from, "to", *to)
+		}
+	}()
+
+	// feed ids
+feed:
+	for id := *from; id <= *to; id++ {
+		select {
+		case ids <- id:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(ids)
+
+	wg.Wait()
+	close(foundCh)
+	<-doneAgg
+
+	// save
+	interrupted := bootstrap.Interrupted(ctx)
+	res := repository.StoresResult{
+		FetchedAt:  time.Now().UTC().Format(time.RFC3339),
+		Stores:     stores,
+		Count:      len(stores),
+		Incomplete: interrupted,
+	}
+	if interrupted {
+		log.Warn("scan interrupted, saving partial result", "scanned", atomic.LoadUint64(&scanned), "found", len(stores))
+	}
+	// формат по расширению -out: .json, .csv, .sql, ...
+	repo, err := sinks.New(cfg, config.SinkConfig{Path: *outPath}, log)
+	if err != nil {
+		log.Error("init output failed", "err", err)
+		return exitFailure
+	}
+	// запись не отменяется сигналом
+	if err := repo.SaveStores(context.WithoutCancel(ctx), res); err != nil {
+		log.Error("save stores failed", "err", err)
+		return exitFailure
+	}
+
+	log.Info("done", "scanned", atomic.LoadUint64(&scanned), "found", len(stores), "out", *outPath, "incomplete", interrupted)
+	if interrupted {
+		return bootstrap.ExitInterrupted
+	}
+	return exitOK
+}
@@ -0,0 +1,275 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Job — одна выгрузка: категория магазина или весь каталог (All).
+type Job struct {
+	StoreID    int
+	CategoryID int
+	All        bool
+}
+
+func (j Job) String() string {
+	return fmt.Sprintf("store=%d category=%s", j.StoreID, j.Category())
+}
+
+// Key — ключ задания в чекпоинте: "86/68499", "86/all".
+func (j Job) Key() string {
+	return strconv.Itoa(j.StoreID) + "/" + j.Category()
+}
+
+// Category — id категории или "all".
+func (j Job) Category() string {
+	if j.All {
+		return "all"
+	}
+	return strconv.Itoa(j.CategoryID)
+}
+
+// File — содержимое файла заданий.
+type File struct {
+	Output string // шаблон пути выгрузки; пусто — из флага
+	Jobs   []Job
+}
+
+// yaml:
+//
+//	output: ./output/{store}/{category}.json # необязательно
+//	jobs:
+//	  - {store: 86, category: 68499}
+//	  - {store: 86, category: all}
+type yamlFile struct {
+	Output string `yaml:"output"`
+	Jobs   []struct {
+		Store    int    `yaml:"store"`
+		Category string `yaml:"category"`
+	} `yaml:"jobs"`
+}
+
+// Load читает файл заданий: .csv (store,category; заголовок необязателен)
+// или yaml. Повторяющиеся задания выполняются один раз.
+func Load(path string) (File, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, err
+	}
+
+	var f File
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		f.Jobs, err = parseCSV(b)
+	default:
+		f, err = parseYAML(b)
+	}
+	if err != nil {
+		return File{}, fmt.Errorf("jobs %s: %w", path, err)
+	}
+	if len(f.Jobs) == 0 {
+		return File{}, fmt.Errorf("jobs %s: no jobs", path)
+	}
+	f.Jobs = unique(f.Jobs)
+	return f, nil
+}
+
+func parseYAML(b []byte) (File, error) {
+	var y yamlFile
+	if err := yaml.Unmarshal(b, &y); err != nil {
+		return File{}, err
+	}
+	f := File{Output: y.Output}
+	for i, j := range y.Jobs {
+		job, err := parseJob(j.Store, j.Category)
+		if err != nil {
+			return File{}, fmt.Errorf("jobs[%d]: %w", i, err)
+		}
+		f.Jobs = append(f.Jobs, job)
+	}
+	return f, nil
+}
+
+func parseCSV(b []byte) ([]Job, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Job
+	for i, row := range rows {
+		if len(row) < 2 {
+			return nil, fmt.Errorf("line %d: expected store,category", i+1)
+		}
+		store, err := strconv.Atoi(strings.TrimSpace(row[0]))
+		if err != nil {
+			if i == 0 {
+				continue // заголовок
+			}
+			return nil, fmt.Errorf("line %d: store must be integer, got %q", i+1, row[0])
+		}
+		job, err := parseJob(store, row[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		out = append(out, job)
+	}
+	return out, nil
+}
+
+func parseJob(store int, category string) (Job, error) {
+	if store <= 0 {
+		return Job{}, fmt.Errorf("store must be > 0, got %d", store)
+	}
+	category = strings.TrimSpace(category)
+	if strings.EqualFold(category, "all") {
+		return Job{StoreID: store, All: true}, nil
+	}
+	id, err := strconv.Atoi(category)
+	if err != nil || id <= 0 {
+		return Job{}, fmt.Errorf("category must be > 0 or \"all\", got %q", category)
+	}
+	return Job{StoreID: store, CategoryID: id}, nil
+}
+
+func unique(jobs []Job) []Job {
+	seen := make(map[Job]bool, len(jobs))
+	out := jobs[:0]
+	for _, j := range jobs {
+		if !seen[j] {
+			seen[j] = true
+			out = append(out, j)
+		}
+	}
+	return out
+}
+
+// Render подставляет в шаблон пути {store}, {category} (id или all) и {date} (UTC).
+func Render(template string, j Job, now time.Time) string {
+	return strings.NewReplacer(
+		"{store}", strconv.Itoa(j.StoreID),
+		"{category}", j.Category(),
+		"{date}", now.UTC().Format("2006-01-02"),
+	).Replace(template)
+}
+
+// CheckOutputs — у каждого задания свой выход: шаблон, по которому два
+// задания пишут в один путь (нет {store} или {category}), отклоняется
+// до запуска, иначе задания перетирали бы файлы друг друга.
+func CheckOutputs(template string, jobs []Job, now time.Time) error {
+	seen := make(map[string]Job, len(jobs))
+	for _, j := range jobs {
+		path := Render(template, j, now)
+		key := filepath.Clean(path)
+		if prev, ok := seen[key]; ok {
+			return fmt.Errorf("jobs %s and %s write to the same output %s: add {store} and {category} to the template", prev.Key(), j.Key(), path)
+		}
+		seen[key] = j
+	}
+	return nil
+}
+
+type Result struct {
+	Job      Job
+	Output   string
+	Count    int
+	Err      error
+	Duration time.Duration
+	Resumed  bool // выполнено в прошлом запуске (чекпоинт)
+}
+
+// JobFunc выполняет одно задание и возвращает число товаров.
+type JobFunc func(ctx context.Context, j Job) (int, error)
+
+// Run выполняет задания не более чем в workers горутин; результаты —
+// в порядке заданий. Отмена ctx: новые задания не начинаются, у
+// неначатых — причина отмены ctx.
+func Run(ctx context.Context, jobs []Job, workers int, fn JobFunc) []Result {
+	if workers <= 0 {
+		workers = 1
+	}
+	results := make([]Result, len(jobs))
+
+	idx := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(jobs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range idx {
+				start := time.Now()
+				n, err := fn(ctx, jobs[i])
+				results[i] = Result{Job: jobs[i], Count: n, Err: err, Duration: time.Since(start)}
+			}
+		}()
+	}
+
+feed:
+	for i := range jobs {
+		select {
+		case idx <- i:
+		case <-ctx.Done():
+			for ; i < len(jobs); i++ {
+				results[i] = Result{Job: jobs[i], Err: context.Cause(ctx)}
+			}
+			break feed
+		}
+	}
+	close(idx)
+	wg.Wait()
+	return results
+}
+
+// Failed — сколько заданий завершилось ошибкой.
+func Failed(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if r.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteSummary печатает таблицу по заданиям и итог.
+func WriteSummary(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STORE\tCATEGORY\tSTATUS\tCOUNT\tDURATION\tOUTPUT / ERROR")
+
+	total := 0
+	for _, r := range results {
+		status, detail := "ok", r.Output
+		switch {
+		case r.Err != nil:
+			status, detail = "failed", r.Err.Error()
+		case r.Resumed:
+			status = "resumed"
+		}
+		total += r.Count
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\n",
+			r.Job.StoreID, r.Job.Category(), status, r.Count, r.Duration.Round(time.Millisecond), detail)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	failed := Failed(results)
+	_, err := fmt.Fprintf(w, "\n%d/%d jobs ok, %d failed, %d products\n", len(results)-failed, len(results), failed, total)
+	return err
+}
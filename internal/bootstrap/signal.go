@@ -0,0 +1,59 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// ErrInterrupted — причина отмены контекста из SignalContext.
+var ErrInterrupted = errors.New("interrupted by signal")
+
+// ExitInterrupted — код выхода после остановки по сигналу (128 + SIGINT).
+const ExitInterrupted = 130
+
+// SignalContext отменяет ctx по первому SIGINT/SIGTERM (причина —
+// ErrInterrupted): новые запросы не уходят, начатые дожидаются, и
+// вызывающий сохраняет то, что успел собрать. Второй сигнал завершает
+// процесс сразу. stop снимает обработчик.
+func SignalContext(parent context.Context, log *slog.Logger) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case s := <-sig:
+			log.Warn("stopping: waiting for in-flight requests, partial result will be saved (repeat to force exit)", "signal", s.String())
+			cancel(ErrInterrupted)
+		case <-done:
+			return
+		}
+		select {
+		case s := <-sig:
+			log.Error("forced exit", "signal", s.String())
+			os.Exit(ExitInterrupted)
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			signal.Stop(sig)
+			close(done)
+			cancel(context.Canceled)
+		})
+	}
+}
+
+// Interrupted — ctx (или его родитель) отменён сигналом из SignalContext.
+func Interrupted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrInterrupted)
+}
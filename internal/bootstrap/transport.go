@@ -2,19 +2,51 @@ package bootstrap
 
 import (
 	"kuperparser/internal/client"
+	"kuperparser/internal/client/httpc"
 	"kuperparser/internal/client/proxy"
 	"kuperparser/internal/client/transport"
 	"kuperparser/internal/config"
+	"kuperparser/internal/redact"
 	"log/slog"
+	"net/http"
+	"net/url"
 	"time"
 )
 
-func BuildTransport(profile *config.Config, log *slog.Logger, concurrency int) (transport.Transport, error) {
+func BuildTransport(profile *config.Config, log *slog.Logger, concurrency int) (transport.Transport, *proxy.Health, error) {
+	health := NewProxyHealth(profile)
+	tr, err := BuildTransportWithHealth(profile, log, concurrency, health)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tr, health, nil
+}
+
+func NewProxyHealth(profile *config.Config) *proxy.Health {
+	return proxy.NewHealth(proxyHealthOptions(profile))
+}
+
+func proxyHealthOptions(profile *config.Config) proxy.HealthOptions {
+	return proxy.HealthOptions{
+		EjectAfter: profile.Proxy.EjectAfter,
+		EjectFor:   time.Duration(profile.Proxy.EjectSeconds) * time.Second,
+	}
+}
+
+// BuildTransportWithHealth собирает транспорт с уже существующей статистикой
+// прокси — при перезагрузке конфига счётчики и ejection не сбрасываются.
+func BuildTransportWithHealth(profile *config.Config, log *slog.Logger, concurrency int, health *proxy.Health) (transport.Transport, error) {
+	health.SetOptions(proxyHealthOptions(profile))
+
 	log.Info("profile",
 		"env", profile.Env,
 		"proxy_mode", profile.Proxy.Mode,
 		"proxy_list_len", len(profile.Proxy.List),
 	)
+	log.Debug("proxy config",
+		"list", redact.URLs(profile.Proxy.List),
+		"rotation_url", redact.URLStrict(profile.Proxy.RotationURL),
+	)
 
 	pvd, failOpen, err := proxy.FromConfig(proxy.Config{
 		Mode:               profile.Proxy.Mode,
@@ -22,6 +54,7 @@ func BuildTransport(profile *config.Config, log *slog.Logger, concurrency int) (
 		RotationURL:        profile.Proxy.RotationURL,
 		RotationTTLSeconds: profile.Proxy.RotationTTLSeconds,
 		FailOpen:           profile.Proxy.FailOpen,
+		Health:             health,
 	}, log)
 	if err != nil {
 		return nil, err
@@ -35,10 +68,13 @@ func BuildTransport(profile *config.Config, log *slog.Logger, concurrency int) (
 		log.Info("proxy ON", "mode", profile.Proxy.Mode, "fail_open", profile.Proxy.FailOpen)
 	}
 
-	httpClient := client.NewHTTPClientWithProxy(
-		time.Duration(profile.HTTP.TimeoutSeconds)*time.Second,
-		proxyFunc,
-	)
+	httpClient, err := client.NewHTTPClientWithOptions(httpOptions(profile, proxyFunc))
+	if err != nil {
+		return nil, err
+	}
+	if proxyFunc != nil {
+		httpClient.Transport = proxy.Track(httpClient.Transport, health)
+	}
 
 	return transport.Build(transport.Options{
 		HTTPClient:  httpClient,
@@ -47,3 +83,34 @@ func BuildTransport(profile *config.Config, log *slog.Logger, concurrency int) (
 		Logger:      log,
 	})
 }
+
+func httpOptions(profile *config.Config, proxyFunc func(*http.Request) (*url.URL, error)) client.HTTPOptions {
+	tc := profile.HTTP.Transport
+	sec := func(n int) time.Duration { return time.Duration(n) * time.Second }
+
+	// версия уже проверена в config.Validate
+	minTLS, _ := httpc.ParseTLSVersion(tc.TLS.MinVersion)
+
+	return client.HTTPOptions{
+		Timeout: sec(profile.HTTP.TimeoutSeconds),
+		Proxy:   proxyFunc,
+
+		DialTimeout:           sec(tc.DialTimeoutSeconds),
+		KeepAlive:             sec(tc.KeepAliveSeconds),
+		DisableKeepAlives:     tc.DisableKeepAlives,
+		TLSHandshakeTimeout:   sec(tc.TLSHandshakeTimeoutSeconds),
+		ResponseHeaderTimeout: sec(tc.ResponseHeaderTimeoutSeconds),
+		ExpectContinueTimeout: sec(tc.ExpectContinueTimeoutSeconds),
+		IdleConnTimeout:       sec(tc.IdleConnTimeoutSeconds),
+
+		MaxIdleConns:        tc.MaxIdleConns,
+		MaxIdleConnsPerHost: tc.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     tc.MaxConnsPerHost,
+
+		DisableHTTP2:        tc.DisableHTTP2,
+		DisableHTTP2Proxies: tc.DisableHTTP2Proxies,
+
+		TLSMinVersion: minTLS,
+		CAFile:        tc.TLS.CAFile,
+	}
+}
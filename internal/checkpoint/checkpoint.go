@@ -0,0 +1,367 @@
+package checkpoint
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kuperparser/internal/apis/kuper/usecases"
+	"kuperparser/internal/repository/atomicfile"
+)
+
+const version = 2
+
+// saveEvery — как часто курсоры страниц сбрасываются на диск; завершение
+// раздела, задания и Flush пишут сразу.
+const saveEvery = 2 * time.Second
+
+// Checkpoint — прогресс долгой выгрузки на диске: завершённые задания и
+// последняя загруженная страница каждого раздела. Сами товары загруженных
+// страниц дописываются в <path>.items/ — NDJSON на раздел, строка на
+// страницу, — и в памяти не копятся. Безопасен для параллельных заданий.
+// nil *Checkpoint — чекпоинт выключен: методы ничего не делают.
+type Checkpoint struct {
+	path string
+
+	mu       sync.Mutex
+	state    file
+	lastSave time.Time
+}
+
+type file struct {
+	Version   int               `json:"version"`
+	Params    map[string]string `json:"params"`
+	UpdatedAt string            `json:"updated_at"`
+	Jobs      map[string]*job   `json:"jobs"`
+}
+
+type job struct {
+	Done        bool                   `json:"done,omitempty"`
+	Count       int                    `json:"count,omitempty"`
+	Output      string                 `json:"output,omitempty"`
+	Departments map[string]*department `json:"departments,omitempty"`
+}
+
+type department struct {
+	Page int  `json:"page"`
+	Done bool `json:"done,omitempty"`
+}
+
+// pageLine — строка файла товаров раздела.
+type pageLine struct {
+	Page  int             `json:"page"`
+	Items []usecases.Item `json:"items"`
+}
+
+// Completed — итог задания, завершённого в прошлом запуске.
+type Completed struct {
+	Count  int
+	Output string
+}
+
+// Open: resume — продолжить из существующего файла; params (настройки,
+// от которых зависят страницы и товары) должны совпасть с сохранёнными.
+// Без resume (или без файла) начинается новый чекпоинт.
+func Open(path string, params map[string]string, resume bool, log *slog.Logger) (*Checkpoint, error) {
+	if log == nil {
+		log = slog.Default()
+	}
+	c := &Checkpoint{
+		path:  path,
+		state: file{Version: version, Params: params, Jobs: make(map[string]*job)},
+	}
+
+	b, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if resume {
+			log.Warn("no checkpoint to resume from, starting from scratch", "path", path)
+		}
+		return c, c.resetItems()
+	case err != nil:
+		return nil, fmt.Errorf("checkpoint: %w", err)
+	case !resume:
+		log.Info("existing checkpoint is replaced (use -resume to continue it)", "path", path)
+		return c, c.resetItems()
+	}
+
+	var st file
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	if st.Version != version {
+		return nil, fmt.Errorf("checkpoint %s: version %d is not supported (expected %d)", path, st.Version, version)
+	}
+	if diffs := mismatch(st.Params, params); len(diffs) > 0 {
+		return nil, fmt.Errorf("checkpoint %s does not match current config: %s", path, strings.Join(diffs, "; "))
+	}
+	if st.Jobs == nil {
+		st.Jobs = make(map[string]*job)
+	}
+	c.state = st
+
+	done := 0
+	for _, j := range st.Jobs {
+		if j.Done {
+			done++
+		}
+	}
+	log.Info("resume from checkpoint", "path", path, "updated_at", st.UpdatedAt, "jobs", len(st.Jobs), "jobs_done", done)
+	return c, nil
+}
+
+func mismatch(saved, cur map[string]string) []string {
+	keys := make(map[string]bool)
+	for k := range saved {
+		keys[k] = true
+	}
+	for k := range cur {
+		keys[k] = true
+	}
+
+	var out []string
+	for k := range keys {
+		if saved[k] != cur[k] {
+			out = append(out, fmt.Sprintf("%s was %q, now %q", k, saved[k], cur[k]))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (c *Checkpoint) Path() string {
+	if c == nil {
+		return ""
+	}
+	return c.path
+}
+
+// Completed — задание key уже выполнено в прошлом запуске.
+func (c *Checkpoint) Completed(key string) (Completed, bool) {
+	if c == nil {
+		return Completed{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	j, ok := c.state.Jobs[key]
+	if !ok || !j.Done {
+		return Completed{}, false
+	}
+	return Completed{Count: j.Count, Output: j.Output}, true
+}
+
+// Complete отмечает задание выполненным; товары его разделов больше не нужны.
+func (c *Checkpoint) Complete(key string, count int, output string) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if j, ok := c.state.Jobs[key]; ok {
+		for slug := range j.Departments {
+			if err := os.Remove(c.itemsPath(key, slug)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("checkpoint: %w", err)
+			}
+		}
+	}
+	c.state.Jobs[key] = &job{Done: true, Count: count, Output: output}
+	return c.saveLocked()
+}
+
+// Progress — прогресс разделов задания key для CategoryProductsService.WithProgress.
+func (c *Checkpoint) Progress(key string) usecases.Progress {
+	if c == nil {
+		return nil
+	}
+	return &progress{c: c, key: key}
+}
+
+// Flush пишет несохранённый прогресс (при ошибке или остановке).
+func (c *Checkpoint) Flush() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saveLocked()
+}
+
+// Remove удаляет файл и товары после успешной выгрузки.
+func (c *Checkpoint) Remove() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	if err := os.RemoveAll(c.itemsDir()); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (c *Checkpoint) itemsDir() string { return c.path + ".items" }
+
+// itemsPath — файл товаров раздела: ключ задания (86/68499) и slug
+// в имени файла без разделителей пути.
+func (c *Checkpoint) itemsPath(key, slug string) string {
+	name := strings.NewReplacer("/", "_", string(filepath.Separator), "_").Replace(key + "__" + slug)
+	return filepath.Join(c.itemsDir(), name+".ndjson")
+}
+
+// resetItems — новый чекпоинт: товары прошлого запуска не нужны.
+func (c *Checkpoint) resetItems() error {
+	if err := os.RemoveAll(c.itemsDir()); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (c *Checkpoint) department(key, slug string) *department {
+	j, ok := c.state.Jobs[key]
+	if !ok {
+		j = &job{}
+		c.state.Jobs[key] = j
+	}
+	if j.Departments == nil {
+		j.Departments = make(map[string]*department)
+	}
+	d, ok := j.Departments[slug]
+	if !ok {
+		d = &department{}
+		j.Departments[slug] = d
+	}
+	return d
+}
+
+func (c *Checkpoint) saveLocked() error {
+	c.state.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	b, err := json.Marshal(c.state)
+	if err != nil {
+		return err
+	}
+	err = atomicfile.WriteWith(c.path, atomicfile.Options{Lock: true}, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	c.lastSave = time.Now()
+	return nil
+}
+
+type progress struct {
+	c   *Checkpoint
+	key string
+}
+
+// Resume читает страницы раздела до сохранённого курсора. Строки дальше
+// курсора (дописаны, но курсор не успел сохраниться) отрезаются: эти
+// страницы загрузятся заново и допишутся ещё раз.
+func (p *progress) Resume(slug string, fn func(items []usecases.Item) error) (int, bool, error) {
+	p.c.mu.Lock()
+	var d department
+	if j, ok := p.c.state.Jobs[p.key]; ok && j.Departments[slug] != nil {
+		d = *j.Departments[slug]
+	}
+	path := p.c.itemsPath(p.key, slug)
+	p.c.mu.Unlock()
+
+	if d.Page == 0 {
+		// страниц нет, но файл мог остаться от запуска без сохранённого курсора
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, false, fmt.Errorf("checkpoint: %w", err)
+		}
+		return 0, false, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return 0, false, fmt.Errorf("checkpoint: %w", err)
+	}
+	defer f.Close()
+
+	var (
+		r    = bufio.NewReader(f)
+		keep int64
+		last int
+	)
+	for {
+		b, err := r.ReadBytes('\n')
+		if len(b) == 0 || err != nil && !errors.Is(err, io.EOF) {
+			break
+		}
+		var line pageLine
+		// недописанная строка (сбой посреди записи) — конец файла
+		if json.Unmarshal(b, &line) != nil || line.Page > d.Page {
+			break
+		}
+		if err := fn(line.Items); err != nil {
+			return 0, false, err
+		}
+		keep += int64(len(b))
+		last = line.Page
+	}
+	if last != d.Page {
+		return 0, false, fmt.Errorf("checkpoint: %s has pages up to %d, expected %d", path, last, d.Page)
+	}
+	if err := f.Truncate(keep); err != nil {
+		return 0, false, fmt.Errorf("checkpoint: %w", err)
+	}
+	return d.Page, d.Done, nil
+}
+
+// SavePage дописывает товары страницы в файл раздела сразу, а курсор
+// сохраняется не чаще saveEvery.
+func (p *progress) SavePage(slug string, page int, items []usecases.Item) error {
+	p.c.mu.Lock()
+	defer p.c.mu.Unlock()
+
+	b, err := json.Marshal(pageLine{Page: page, Items: items})
+	if err != nil {
+		return err
+	}
+	if err := appendLine(p.c.itemsPath(p.key, slug), b); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+
+	p.c.department(p.key, slug).Page = page
+	if time.Since(p.c.lastSave) < saveEvery {
+		return nil
+	}
+	return p.c.saveLocked()
+}
+
+func (p *progress) Done(slug string) error {
+	p.c.mu.Lock()
+	defer p.c.mu.Unlock()
+	p.c.department(p.key, slug).Done = true
+	return p.c.saveLocked()
+}
+
+func appendLine(path string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
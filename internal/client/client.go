@@ -12,6 +12,8 @@ import (
 
 type Transport = transport.Transport
 
+type HTTPOptions = httpc.Options
+
 type Options struct {
 	HTTPClient *http.Client
 	Retries    int
@@ -42,6 +44,10 @@ func NewHTTPClientWithProxy(timeout time.Duration, proxyFunc func(*http.Request)
 	return httpc.NewWithProxy(timeout, proxyFunc)
 }
 
+func NewHTTPClientWithOptions(opts HTTPOptions) (*http.Client, error) {
+	return httpc.NewWithOptions(opts)
+}
+
 func ProxyFuncFromProvider(p proxy.Provider, failOpen bool, log *slog.Logger) func(*http.Request) (*url.URL, error) {
 	return proxy.FromProvider(p, failOpen, log)
 }
@@ -1,43 +1,227 @@
 package httpc
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"net"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"os"
+	"strings"
 	"time"
 )
 
+// Options — тюнинг http.Transport. Нулевые значения заменяются дефолтами.
+type Options struct {
+	Timeout time.Duration
+	Proxy   func(*http.Request) (*url.URL, error)
+
+	DialTimeout           time.Duration
+	KeepAlive             time.Duration
+	DisableKeepAlives     bool
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	ExpectContinueTimeout time.Duration
+	IdleConnTimeout       time.Duration
+
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+
+	DisableHTTP2 bool
+	// DisableHTTP2Proxies — прокси (host:port или url), через которые ходим
+	// только по HTTP/1.1: часть MITM-прокси ломает h2.
+	DisableHTTP2Proxies []string
+
+	TLSMinVersion uint16 // tls.VersionTLS12 и т.п., 0 — дефолт Go
+	CAFile        string // доп. CA (PEM), например для перехватывающего прокси
+}
+
+func (o *Options) applyDefaults() {
+	if o.DialTimeout <= 0 {
+		o.DialTimeout = 10 * time.Second
+	}
+	if o.KeepAlive == 0 {
+		o.KeepAlive = 30 * time.Second
+	}
+	if o.TLSHandshakeTimeout <= 0 {
+		o.TLSHandshakeTimeout = 10 * time.Second
+	}
+	if o.ResponseHeaderTimeout <= 0 {
+		o.ResponseHeaderTimeout = 15 * time.Second
+	}
+	if o.ExpectContinueTimeout <= 0 {
+		o.ExpectContinueTimeout = 1 * time.Second
+	}
+	if o.IdleConnTimeout <= 0 {
+		o.IdleConnTimeout = 90 * time.Second
+	}
+	if o.MaxIdleConns <= 0 {
+		o.MaxIdleConns = 100
+	}
+	if o.MaxIdleConnsPerHost <= 0 {
+		o.MaxIdleConnsPerHost = 20
+	}
+}
+
 func New(timeout time.Duration) *http.Client {
 	return NewWithProxy(timeout, nil)
 }
 
 func NewWithProxy(timeout time.Duration, proxyFunc func(*http.Request) (*url.URL, error)) *http.Client {
+	// без CAFile ошибок быть не может
+	c, _ := NewWithOptions(Options{Timeout: timeout, Proxy: proxyFunc})
+	return c
+}
+
+func NewWithOptions(opts Options) (*http.Client, error) {
+	opts.applyDefaults()
+
 	// тк proxyfunc возвращает ошибку,nil opt => всегда будет nil
 	jar, _ := cookiejar.New(nil)
 
-	// TODO: вынести в config
+	tlsCfg := &tls.Config{MinVersion: opts.TLSMinVersion}
+	if opts.CAFile != "" {
+		pool, err := loadCertPool(opts.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	var rt http.RoundTripper
+	if len(opts.DisableHTTP2Proxies) > 0 && opts.Proxy != nil && !opts.DisableHTTP2 {
+		rt = newSplitTransport(opts, tlsCfg)
+	} else {
+		rt = newTransport(opts, tlsCfg, opts.Proxy, !opts.DisableHTTP2)
+	}
+
+	return &http.Client{
+		Transport: rt,
+		Timeout:   opts.Timeout,
+		Jar:       jar,
+	}, nil
+}
+
+func newTransport(opts Options, tlsCfg *tls.Config, proxyFunc func(*http.Request) (*url.URL, error), http2 bool) *http.Transport {
 	tr := &http.Transport{
 		Proxy: proxyFunc,
 		DialContext: (&net.Dialer{
-			Timeout:   10 * time.Second,
-			KeepAlive: 30 * time.Second,
+			Timeout:   opts.DialTimeout,
+			KeepAlive: opts.KeepAlive,
 		}).DialContext,
 
-		TLSHandshakeTimeout:   10 * time.Second,
-		ResponseHeaderTimeout: 15 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       tlsCfg.Clone(),
+		TLSHandshakeTimeout:   opts.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: opts.ResponseHeaderTimeout,
+		ExpectContinueTimeout: opts.ExpectContinueTimeout,
 
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 20,
-		IdleConnTimeout:     90 * time.Second,
+		DisableKeepAlives:   opts.DisableKeepAlives,
+		MaxIdleConns:        opts.MaxIdleConns,
+		MaxIdleConnsPerHost: opts.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     opts.MaxConnsPerHost,
+		IdleConnTimeout:     opts.IdleConnTimeout,
 
-		ForceAttemptHTTP2: true,
+		ForceAttemptHTTP2: http2,
+	}
+	if !http2 {
+		// непустая мапа отключает автоматический h2 в net/http
+		tr.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
 	}
+	return tr
+}
 
-	return &http.Client{
-		Transport: tr,
-		Timeout:   timeout,
-		Jar:       jar,
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read ca_file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("ca_file %s: no PEM certificates found", path)
+	}
+	return pool, nil
+}
+
+// ParseTLSVersion: "1.0".."1.3" → tls.VersionTLS10..13, "" → 0.
+func ParseTLSVersion(s string) (uint16, error) {
+	switch strings.TrimSpace(s) {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unknown tls version %q (expected 1.0|1.1|1.2|1.3)", s)
+	}
+}
+
+// splitTransport выбирает прокси сам (один раз на запрос) и отправляет запрос
+// в h1-only транспорт, если для этого прокси HTTP/2 отключён.
+
+type pinnedProxyKey struct{}
+
+type splitTransport struct {
+	proxy func(*http.Request) (*url.URL, error)
+	h2    *http.Transport
+	h1    *http.Transport
+	noH2  map[string]bool // host:port прокси
+}
+
+func newSplitTransport(opts Options, tlsCfg *tls.Config) *splitTransport {
+	s := &splitTransport{proxy: opts.Proxy, noH2: make(map[string]bool)}
+	for _, raw := range opts.DisableHTTP2Proxies {
+		s.noH2[proxyHost(raw)] = true
 	}
+	s.h2 = newTransport(opts, tlsCfg, s.pinned, true)
+	s.h1 = newTransport(opts, tlsCfg, s.pinned, false)
+	return s
+}
+
+func proxyHost(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return raw
+}
+
+func (s *splitTransport) pinned(req *http.Request) (*url.URL, error) {
+	if u, ok := req.Context().Value(pinnedProxyKey{}).(*url.URL); ok {
+		return u, nil
+	}
+	return s.proxy(req)
+}
+
+func (s *splitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u, err := s.proxy(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// закрепляем выбор, чтобы транспорт не спрашивал провайдер повторно (nil — без прокси)
+	req = req.WithContext(context.WithValue(req.Context(), pinnedProxyKey{}, u))
+	if u != nil && s.noH2[u.Host] {
+		return s.h1.RoundTrip(req)
+	}
+	return s.h2.RoundTrip(req)
+}
+
+func (s *splitTransport) CloseIdleConnections() {
+	s.h1.CloseIdleConnections()
+	s.h2.CloseIdleConnections()
 }
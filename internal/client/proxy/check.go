@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type CheckResult struct {
+	Proxy   string
+	Status  int
+	Latency time.Duration
+	Err     error
+}
+
+func (r CheckResult) OK() bool {
+	return r.Err == nil && r.Status >= 200 && r.Status < 400
+}
+
+// Check прогоняет GET target через каждый прокси из списка и возвращает
+// результаты в том же порядке.
+func Check(ctx context.Context, proxies []string, target string, timeout time.Duration, concurrency int) []CheckResult {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	if concurrency <= 0 {
+		concurrency = 8
+	}
+
+	out := make([]CheckResult, len(proxies))
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, raw := range proxies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				out[i] = CheckResult{Proxy: Key(raw), Err: ctx.Err()}
+				return
+			}
+			defer func() { <-sem }()
+
+			out[i] = checkOne(ctx, raw, target, timeout)
+		}()
+	}
+	wg.Wait()
+
+	return out
+}
+
+func checkOne(ctx context.Context, raw, target string, timeout time.Duration) CheckResult {
+	res := CheckResult{Proxy: Key(raw)}
+
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		res.Err = fmt.Errorf("parse proxy: %w", err)
+		return res
+	}
+
+	c := &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{Proxy: http.ProxyURL(u)},
+	}
+	defer c.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	start := time.Now()
+	resp, err := c.Do(req)
+	res.Latency = time.Since(start)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 32*1024))
+
+	res.Status = resp.StatusCode
+	return res
+}
@@ -0,0 +1,349 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kuperparser/internal/redact"
+)
+
+// staleAfter — статистика прокси не из списка (их выдаёт rotation_url)
+// забывается, если прокси столько не использовался: иначе каждый выданный
+// адрес остался бы в памяти долгоживущего serve навсегда.
+const staleAfter = time.Hour
+
+type HealthOptions struct {
+	EjectAfter int           // сколько ошибок подряд до исключения прокси из ротации
+	EjectFor   time.Duration // на сколько исключаем
+}
+
+// Health собирает статистику по прокси: успехи/ошибки, задержку,
+// последнюю ошибку и состояние исключения (ejection).
+type Health struct {
+	opts HealthOptions
+
+	mu        sync.Mutex
+	items     map[string]*healthEntry
+	order     []string
+	lastSweep time.Time
+}
+
+type healthEntry struct {
+	registered bool // из списка прокси (Register): не устаревает
+
+	successes   uint64
+	failures    uint64
+	consecutive int
+
+	lastErr   string
+	lastErrAt time.Time
+	lastUsed  time.Time
+
+	lastLatency  time.Duration
+	totalLatency time.Duration
+
+	ejectedUntil time.Time
+}
+
+type Status struct {
+	Proxy               string `json:"proxy"`
+	Successes           uint64 `json:"successes"`
+	Failures            uint64 `json:"failures"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	LastError           string `json:"last_error,omitempty"`
+	LastErrorAt         string `json:"last_error_at,omitempty"`
+	LastUsedAt          string `json:"last_used_at,omitempty"`
+	LastLatencyMS       int64  `json:"last_latency_ms"`
+	AvgLatencyMS        int64  `json:"avg_latency_ms"`
+	Ejected             bool   `json:"ejected"`
+	EjectedUntil        string `json:"ejected_until,omitempty"`
+}
+
+func NewHealth(opts HealthOptions) *Health {
+	h := &Health{items: make(map[string]*healthEntry)}
+	h.SetOptions(opts)
+	return h
+}
+
+// SetOptions меняет пороги исключения; накопленная статистика сохраняется.
+func (h *Health) SetOptions(opts HealthOptions) {
+	if h == nil {
+		return
+	}
+	if opts.EjectAfter <= 0 {
+		opts.EjectAfter = 3
+	}
+	if opts.EjectFor <= 0 {
+		opts.EjectFor = time.Minute
+	}
+	h.mu.Lock()
+	h.opts = opts
+	h.mu.Unlock()
+}
+
+// Key нормализует строку прокси до scheme://host, чтобы статистика не
+// зависела от формы записи в конфиге. Прокси с разными логинами на одном
+// хосте — разные ключи: к нему добавляется #хэш userinfo (сам логин и
+// пароль в ключ и статус не попадают).
+func Key(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	key := u.Scheme + "://" + u.Host
+	if u.User != nil {
+		sum := sha256.Sum256([]byte(u.User.String()))
+		key += "#" + hex.EncodeToString(sum[:4])
+	}
+	return key
+}
+
+// Register добавляет прокси в статистику заранее,
+// чтобы в статусе были видны и те, что ещё не использовались.
+func (h *Health) Register(list []string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, raw := range list {
+		h.entryLocked(Key(raw)).registered = true
+	}
+}
+
+// Retain оставляет статистику только прокси из list — после перезагрузки
+// конфига удалённые из списка прокси пропадают из статуса.
+func (h *Health) Retain(list []string) {
+	if h == nil {
+		return
+	}
+	keep := make(map[string]bool, len(list))
+	for _, raw := range list {
+		keep[Key(raw)] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	order := h.order[:0]
+	for _, key := range h.order {
+		if keep[key] {
+			order = append(order, key)
+			continue
+		}
+		delete(h.items, key)
+	}
+	h.order = order
+}
+
+func (h *Health) entryLocked(key string) *healthEntry {
+	e, ok := h.items[key]
+	if !ok {
+		e = &healthEntry{}
+		h.items[key] = e
+		h.order = append(h.order, key)
+	}
+	return e
+}
+
+// sweepLocked удаляет устаревшие записи не из списка; не чаще раза в минуту.
+func (h *Health) sweepLocked(now time.Time) {
+	if now.Sub(h.lastSweep) < time.Minute {
+		return
+	}
+	h.lastSweep = now
+
+	order := h.order[:0]
+	for _, key := range h.order {
+		if e := h.items[key]; !e.registered && now.Sub(e.lastUsed) > staleAfter {
+			delete(h.items, key)
+			continue
+		}
+		order = append(order, key)
+	}
+	h.order = order
+}
+
+// Observe фиксирует результат запроса через прокси.
+func (h *Health) Observe(raw string, latency time.Duration, err error) {
+	if h == nil {
+		return
+	}
+	key := Key(raw)
+	if key == "" {
+		return
+	}
+
+	now := time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sweepLocked(now)
+	e := h.entryLocked(key)
+	e.lastUsed = now
+	e.lastLatency = latency
+
+	if err == nil {
+		e.successes++
+		e.totalLatency += latency
+		e.consecutive = 0
+		e.ejectedUntil = time.Time{}
+		return
+	}
+
+	e.failures++
+	e.consecutive++
+	e.lastErr = redact.Error(err)
+	e.lastErrAt = now
+	if e.consecutive >= h.opts.EjectAfter {
+		e.ejectedUntil = now.Add(h.opts.EjectFor)
+	}
+}
+
+// Ejected сообщает, исключён ли прокси из ротации в данный момент.
+func (h *Health) Ejected(raw string) bool {
+	if h == nil {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e, ok := h.items[Key(raw)]
+	return ok && time.Now().Before(e.ejectedUntil)
+}
+
+func (h *Health) Snapshot() []Status {
+	if h == nil {
+		return nil
+	}
+	now := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	out := make([]Status, 0, len(h.order))
+	for _, key := range h.order {
+		e := h.items[key]
+		st := Status{
+			Proxy:               key,
+			Successes:           e.successes,
+			Failures:            e.failures,
+			ConsecutiveFailures: e.consecutive,
+			LastError:           e.lastErr,
+			LastLatencyMS:       e.lastLatency.Milliseconds(),
+			Ejected:             now.Before(e.ejectedUntil),
+		}
+		if e.successes > 0 {
+			st.AvgLatencyMS = (e.totalLatency / time.Duration(e.successes)).Milliseconds()
+		}
+		if !e.lastErrAt.IsZero() {
+			st.LastErrorAt = e.lastErrAt.UTC().Format(time.RFC3339)
+		}
+		if !e.lastUsed.IsZero() {
+			st.LastUsedAt = e.lastUsed.UTC().Format(time.RFC3339)
+		}
+		if st.Ejected {
+			st.EjectedUntil = e.ejectedUntil.UTC().Format(time.RFC3339)
+		}
+		out = append(out, st)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Proxy < out[j].Proxy })
+	return out
+}
+
+// tracking: RoundTripper кладёт в контекст "ячейку", proxy func (FromProvider)
+// записывает туда выбранный прокси, после ответа результат уходит в Health.
+
+type selectionKey struct{}
+
+// selection хранит адрес прокси как есть: Key от уже нормализованного
+// ключа потерял бы #хэш userinfo (url.Parse читает его как fragment).
+type selection struct {
+	mu  sync.Mutex
+	raw string
+}
+
+func markSelected(ctx context.Context, u *url.URL) {
+	sel, ok := ctx.Value(selectionKey{}).(*selection)
+	if !ok || u == nil {
+		return
+	}
+	sel.mu.Lock()
+	sel.raw = u.String()
+	sel.mu.Unlock()
+}
+
+type trackingTransport struct {
+	base   http.RoundTripper
+	health *Health
+}
+
+// Track оборачивает RoundTripper и собирает статистику по прокси в Health.
+func Track(base http.RoundTripper, h *Health) http.RoundTripper {
+	if h == nil {
+		return base
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &trackingTransport{base: base, health: h}
+}
+
+// CloseIdleConnections пробрасывается в base: иначе http.Client не достанет
+// до пула соединений под обёрткой.
+func (t *trackingTransport) CloseIdleConnections() {
+	if c, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+func (t *trackingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sel := &selection{}
+	req = req.WithContext(context.WithValue(req.Context(), selectionKey{}, sel))
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	latency := time.Since(start)
+
+	sel.mu.Lock()
+	raw := sel.raw
+	sel.mu.Unlock()
+	if raw == "" {
+		return resp, err
+	}
+
+	// отмена со стороны клиента — не вина прокси
+	if err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		return resp, err
+	}
+
+	t.health.Observe(raw, latency, outcome(resp, err))
+	return resp, err
+}
+
+func outcome(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	switch {
+	case resp.StatusCode == http.StatusProxyAuthRequired,
+		resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode >= 500:
+		return errors.New(resp.Status)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package proxy
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -12,6 +13,8 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"kuperparser/internal/redact"
 )
 
 type Provider interface {
@@ -32,6 +35,10 @@ type Config struct {
 	RotationURL        string
 	RotationTTLSeconds int
 	FailOpen           bool
+
+	// Health (опционально) — статистика по прокси; list-провайдер
+	// пропускает исключённые (ejected) прокси.
+	Health *Health
 }
 
 func FromConfig(cfg Config, log *slog.Logger) (Provider, bool, error) {
@@ -48,10 +55,11 @@ func FromConfig(cfg Config, log *slog.Logger) (Provider, bool, error) {
 		return nil, cfg.FailOpen, nil
 
 	case ModeList:
-		p, err := NewListProvider(cfg.List)
+		p, err := newListProvider(cfg.List, cfg.Health)
 		if err != nil {
 			return nil, cfg.FailOpen, err
 		}
+		cfg.Health.Register(cfg.List)
 		log.Info("proxy enabled", "mode", "list", "count", len(cfg.List), "fail_open", cfg.FailOpen)
 		return p, cfg.FailOpen, nil
 
@@ -64,7 +72,7 @@ func FromConfig(cfg Config, log *slog.Logger) (Provider, bool, error) {
 			ttl = 10 * time.Second
 		}
 		p := NewRotationProvider(cfg.RotationURL, ttl, log)
-		log.Info("proxy enabled", "mode", "rotation", "rotation_url", cfg.RotationURL, "ttl", ttl.String(), "fail_open", cfg.FailOpen)
+		log.Info("proxy enabled", "mode", "rotation", "rotation_url", redact.URLStrict(cfg.RotationURL), "ttl", ttl.String(), "fail_open", cfg.FailOpen)
 		return p, cfg.FailOpen, nil
 
 	default:
@@ -89,7 +97,7 @@ func FromProvider(p Provider, failOpen bool, log *slog.Logger) func(*http.Reques
 
 		raw, err := p.Next(ctx)
 		if err != nil {
-			log.Warn("proxy provider error", "err", err)
+			log.Warn("proxy provider error", "err", redact.Error(err))
 			if failOpen {
 				return nil, nil
 			}
@@ -110,24 +118,30 @@ func FromProvider(p Provider, failOpen bool, log *slog.Logger) func(*http.Reques
 
 		u, err := url.Parse(raw)
 		if err != nil {
-			log.Warn("proxy parse failed", "proxy", raw, "err", err)
+			log.Warn("proxy parse failed", "proxy", redact.URL(raw), "err", redact.Error(err))
 			if failOpen {
 				return nil, nil
 			}
 			return nil, err
 		}
 
+		markSelected(ctx, u)
 		log.Debug("proxy selected", "host", u.Host)
 		return u, nil
 	}
 }
 
 type listProvider struct {
-	items []string
-	idx   uint64
+	items  []string
+	idx    uint64
+	health *Health
 }
 
 func NewListProvider(list []string) (Provider, error) {
+	return newListProvider(list, nil)
+}
+
+func newListProvider(list []string, health *Health) (Provider, error) {
 	clean := make([]string, 0, len(list))
 	for _, s := range list {
 		s = strings.TrimSpace(s)
@@ -139,15 +153,24 @@ func NewListProvider(list []string) (Provider, error) {
 	if len(clean) == 0 {
 		return nil, fmt.Errorf("proxy list is empty")
 	}
-	return &listProvider{items: clean}, nil
+	return &listProvider{items: clean, health: health}, nil
 }
 
 func (p *listProvider) Next(ctx context.Context) (string, error) {
 	if err := ctx.Err(); err != nil {
 		return "", err
 	}
+	n := uint64(len(p.items))
 	i := atomic.AddUint64(&p.idx, 1) - 1
-	return p.items[int(i%uint64(len(p.items)))], nil
+
+	// пропускаем исключённые прокси; если исключены все — берём по кругу как есть
+	for k := uint64(0); k < n; k++ {
+		item := p.items[int((i+k)%n)]
+		if !p.health.Ejected(item) {
+			return item, nil
+		}
+	}
+	return p.items[int(i%n)], nil
 }
 
 type rotationProvider struct {
@@ -180,6 +203,16 @@ func NewRotationProvider(rotationURL string, ttl time.Duration, log *slog.Logger
 	}
 }
 
+// strictURLError: в ошибке net/http полный rotation_url, а он целиком секрет —
+// redact.Error у вызывающего скрыл бы только часть query.
+func strictURLError(err error) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		ue.URL = redact.URLStrict(ue.URL)
+	}
+	return err
+}
+
 func (p *rotationProvider) Next(ctx context.Context) (string, error) {
 	if err := ctx.Err(); err != nil {
 		return "", err
@@ -196,13 +229,13 @@ func (p *rotationProvider) Next(ctx context.Context) (string, error) {
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
 	if err != nil {
-		return "", err
+		return "", strictURLError(err)
 	}
 	req.Header.Set("Accept", "application/json, text/plain, */*")
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", err
+		return "", strictURLError(err)
 	}
 	defer resp.Body.Close()
 
@@ -212,7 +245,8 @@ func (p *rotationProvider) Next(ctx context.Context) (string, error) {
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("rotation_url status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(b)))
+		// тело не выводим: сервис ротации может вернуть в нём прокси с паролем или токен
+		return "", fmt.Errorf("rotation_url status=%d (%d bytes body)", resp.StatusCode, len(b))
 	}
 
 	proxyStr := parseRotationBody(b)
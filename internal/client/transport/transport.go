@@ -11,6 +11,8 @@ import (
 	"net/http"
 	"strconv"
 	"time"
+
+	"kuperparser/internal/redact"
 )
 
 type Transport interface {
@@ -88,6 +90,19 @@ func (h *HTTPTransport) Do(req *http.Request) (*http.Response, error) {
 	return h.Client.Do(req)
 }
 
+func (h *HTTPTransport) CloseIdleConnections() {
+	h.Client.CloseIdleConnections()
+}
+
+// CloseIdleConnections закрывает простаивающие соединения под всеми слоями
+// транспорта (старого — после перезагрузки конфига); запросы в полёте
+// не трогаются.
+func CloseIdleConnections(t Transport) {
+	if c, ok := t.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
 // semaphore transport
 
 type semaphore struct {
@@ -128,6 +143,8 @@ func (t *ConcurrencyTransport) Do(req *http.Request) (*http.Response, error) {
 	return t.Base.Do(req)
 }
 
+func (t *ConcurrencyTransport) CloseIdleConnections() { CloseIdleConnections(t.Base) }
+
 type RetryTransport struct {
 	Base       Transport
 	MaxRetries int
@@ -138,6 +155,8 @@ type RetryTransport struct {
 	Log *slog.Logger
 }
 
+func (r *RetryTransport) CloseIdleConnections() { CloseIdleConnections(r.Base) }
+
 func (r *RetryTransport) Do(req *http.Request) (*http.Response, error) {
 	l := r.Log
 	if l == nil {
@@ -171,7 +190,7 @@ func (r *RetryTransport) Do(req *http.Request) (*http.Response, error) {
 				"attempt", attempt+1,
 				"max_attempts", r.MaxRetries+1,
 				"status", resp.StatusCode,
-				"url", req.URL.String(),
+				"url", redact.URL(req.URL.String()),
 			)
 
 			if resp.StatusCode == http.StatusTooManyRequests && attempt < r.MaxRetries {
@@ -192,8 +211,8 @@ func (r *RetryTransport) Do(req *http.Request) (*http.Response, error) {
 			l.Warn("retryable error",
 				"attempt", attempt+1,
 				"max_attempts", r.MaxRetries+1,
-				"err", err,
-				"url", req.URL.String(),
+				"err", redact.Error(err),
+				"url", redact.URL(req.URL.String()),
 			)
 		}
 
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -10,18 +12,82 @@ import (
 
 type ProxyConfig struct {
 	Mode               string   `yaml:"mode"` // disabled|list|rotation
-	List               []string `yaml:"list"`
-	RotationURL        string   `yaml:"rotation_url"`
+	List               []string `yaml:"list" redact:"url"`
+	RotationURL        string   `yaml:"rotation_url" redact:"strict"`
 	RotationTTLSeconds int      `yaml:"rotation_ttl_seconds"`
 	FailOpen           bool     `yaml:"fail_open"`
+	EjectAfter         int      `yaml:"eject_after"`   // ошибок подряд до исключения прокси
+	EjectSeconds       int      `yaml:"eject_seconds"` // на сколько исключаем
 }
 
+// TransportConfig — тюнинг http.Transport (таймауты в секундах).
+type TransportConfig struct {
+	DialTimeoutSeconds           int  `yaml:"dial_timeout_seconds"`
+	KeepAliveSeconds             int  `yaml:"keep_alive_seconds"`
+	DisableKeepAlives            bool `yaml:"disable_keep_alives"`
+	TLSHandshakeTimeoutSeconds   int  `yaml:"tls_handshake_timeout_seconds"`
+	ResponseHeaderTimeoutSeconds int  `yaml:"response_header_timeout_seconds"`
+	ExpectContinueTimeoutSeconds int  `yaml:"expect_continue_timeout_seconds"`
+	IdleConnTimeoutSeconds       int  `yaml:"idle_conn_timeout_seconds"`
+
+	MaxIdleConns        int `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host"`
+	MaxConnsPerHost     int `yaml:"max_conns_per_host"` // 0 — без ограничения
+
+	DisableHTTP2        bool     `yaml:"disable_http2"`
+	DisableHTTP2Proxies []string `yaml:"disable_http2_proxies" redact:"url"`
+
+	TLS struct {
+		MinVersion string `yaml:"min_version"` // 1.0|1.1|1.2|1.3
+		CAFile     string `yaml:"ca_file"`     // доп. CA (PEM) для перехватывающих прокси
+	} `yaml:"tls"`
+}
+
+// Root — схема config.yaml (используется для проверки ключей и типов);
+// сам профиль собирается слиянием слоёв, см. profiles.go.
 type Root struct {
-	Env   string      `yaml:"env"`
-	Proxy ProxyConfig `yaml:"proxy"`
-	Local Config      `yaml:"local"`
-	Dev   Config      `yaml:"dev"`
-	Prod  Config      `yaml:"prod"`
+	Env      string            `yaml:"env"`
+	Proxy    ProxyConfig       `yaml:"proxy"`
+	Defaults Config            `yaml:"defaults"`
+	Local    Config            `yaml:"local"`
+	Dev      Config            `yaml:"dev"`
+	Prod     Config            `yaml:"prod"`
+	Profiles map[string]Config `yaml:"profiles"`
+}
+
+type SinkConfig struct {
+	Type    string `yaml:"type"` // json|ndjson|csv|tsv|xlsx|yml|sql|webhook; пусто — по url/расширению path
+	Path    string `yaml:"path"`
+	OnError string `yaml:"on_error"` // fail|warn; пусто — fail
+
+	// webhook
+	URL        string `yaml:"url" redact:"url"`
+	Secret     string `yaml:"secret" redact:"full"` // ключ HMAC-подписи
+	Gzip       bool   `yaml:"gzip"`
+	BatchSize  int    `yaml:"batch_size"`  // товаров в запросе; 0 — всё одним запросом
+	DeadLetter string `yaml:"dead_letter"` // файл для недоставленных пачек
+}
+
+// AlertRule — правило оповещения. Фильтры (url, name, store_id) сужают
+// товары, условия (change_percent, price_below, back_in_stock) — когда
+// срабатывать; достаточно одного выполненного условия.
+type AlertRule struct {
+	Name    string `yaml:"name"`
+	URL     string `yaml:"url"`      // точный url или шаблон с *
+	Pattern string `yaml:"pattern"`  // регулярное выражение по названию (без учёта регистра)
+	StoreID int    `yaml:"store_id"` // 0 — любой магазин
+
+	ChangePercent float64 `yaml:"change_percent"` // |изменение цены| >= N%
+	Direction     string  `yaml:"direction"`      // down|up|any; пусто — any
+	PriceBelow    float64 `yaml:"price_below"`    // цена ниже X
+	BackInStock   bool    `yaml:"back_in_stock"`  // товара не было в прошлом снимке (или без цены)
+}
+
+type NotifierConfig struct {
+	Type   string `yaml:"type"` // stdout|file|webhook
+	Path   string `yaml:"path"` // file: NDJSON, дописывается
+	URL    string `yaml:"url" redact:"url"`
+	Secret string `yaml:"secret" redact:"full"`
 }
 
 type Config struct {
@@ -36,6 +102,8 @@ type Config struct {
 	Server struct {
 		Host string `yaml:"host"`
 		Port int    `yaml:"port"`
+		// AdminToken — Bearer-токен для /admin/*; пусто — эти маршруты выключены
+		AdminToken string `yaml:"admin_token" redact:"full"`
 	} `yaml:"server"`
 
 	Kuper struct {
@@ -46,61 +114,165 @@ type Config struct {
 	CLI struct {
 		CategoryID int    `yaml:"category_id"`
 		OutputFile string `yaml:"output_file"`
+		Format     string `yaml:"format"` // json|ndjson|csv|tsv|xlsx|yml|sql; пусто — по расширению output_file
+
+		// JobTimeoutSeconds — дедлайн всей выгрузки (задания batch); по нему
+		// сохраняется загруженное с incomplete. 0 — без дедлайна
+		JobTimeoutSeconds int `yaml:"job_timeout_seconds"`
+
+		// CheckpointFile — прогресс выгрузки для -resume; пусто — <output_file>.checkpoint.json
+		// (batch — <файл заданий>.checkpoint.json)
+		CheckpointFile string `yaml:"checkpoint_file"`
+
+		CSV struct {
+			Delimiter string   `yaml:"delimiter"` // "," ";" или "tab"; для tsv всегда tab
+			BOM       bool     `yaml:"bom"`       // UTF-8 BOM для Excel
+			Columns   []string `yaml:"columns"`
+		} `yaml:"csv"`
+
+		JSON struct {
+			Lock    bool `yaml:"lock"`    // advisory-блокировка файла на время записи
+			Backups int  `yaml:"backups"` // сколько предыдущих выгрузок хранить (out.json.1, ...)
+		} `yaml:"json"`
+
+		SQL struct {
+			Dialect   string `yaml:"dialect"`    // postgres|sqlite
+			BatchSize int    `yaml:"batch_size"` // строк на INSERT
+			Copy      bool   `yaml:"copy"`       // postgres: COPY FROM stdin вместо INSERT
+		} `yaml:"sql"`
+
+		// Sinks — дополнительные выходы, пишутся вместе с output_file
+		Sinks []SinkConfig `yaml:"sinks"`
 	} `yaml:"cli"`
 
 	Pagination struct {
 		PerPage     int `yaml:"per_page"`
 		OffersLimit int `yaml:"offers_limit"`
 		MaxPages    int `yaml:"max_pages"`
+		// PageTimeoutSeconds — на одну страницу вместе с ретраями; 0 — без лимита
+		PageTimeoutSeconds int `yaml:"page_timeout_seconds"`
 	} `yaml:"pagination"`
 
 	HTTP struct {
-		TimeoutSeconds int `yaml:"timeout_seconds"`
-		Retries        int `yaml:"retries"`
+		TimeoutSeconds int             `yaml:"timeout_seconds"` // на один HTTP-запрос
+		Retries        int             `yaml:"retries"`
+		Transport      TransportConfig `yaml:"transport"`
 	} `yaml:"http"`
 
 	Proxy ProxyConfig `yaml:"proxy"`
+
+	// History — локальная история цен (CLI и API дописывают каждый результат)
+	History struct {
+		Dir string `yaml:"dir"` // пусто — история не пишется
+	} `yaml:"history"`
+
+	// Alerts — правила проверяются после каждой выгрузки CLI против прошлого
+	// снимка категории (из history.dir, иначе — прежний cli.output_file)
+	Alerts struct {
+		StateFile string           `yaml:"state_file"` // уже отправленные оповещения (дедупликация)
+		Rules     []AlertRule      `yaml:"rules"`
+		Notifiers []NotifierConfig `yaml:"notifiers"` // пусто — stdout
+	} `yaml:"alerts"`
+
+	origins map[string]origin // откуда пришло значение поля (для сообщений Validate)
+}
+
+// LoadOptions — слои поверх config.yaml.
+type LoadOptions struct {
+	Env       string     // профиль; пусто — KUPERPARSER_ENV из Environ, затем ключ env в yaml
+	Environ   []string   // переменные окружения (KEY=VALUE), учитываются KUPERPARSER_*
+	Overrides *Overrides // значения из флагов
 }
 
+// Load читает config.yaml и применяет переменные окружения KUPERPARSER_*.
 func Load(path string) (*Config, error) {
+	return LoadWith(path, LoadOptions{Environ: os.Environ()})
+}
+
+func LoadWith(path string, opts LoadOptions) (*Config, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	var root Root
-	if err := yaml.Unmarshal(b, &root); err != nil {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	lines := make(map[string]int)
+	problems := inspect(&doc, reflect.TypeOf(Root{}), "", lines)
+
+	want := opts.Env
+	if want == "" {
+		want = lookupEnv(opts.Environ, EnvPrefix+"_ENV")
+	}
+	env, layers, err := selectProfile(&doc, want)
+	if err != nil {
 		return nil, err
 	}
 
-	env := strings.TrimSpace(strings.ToLower(root.Env))
-	if env == "" {
-		env = "local"
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, l := range layers {
+		merged = merge(merged, l.node)
+	}
+	// секреты раскрываем только в выбранном профиле
+	if err := resolveSecrets(merged, filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("resolve secrets: %w", err)
 	}
 
 	var p Config
-	switch env {
-	case "local":
-		p = root.Local
-	case "dev":
-		p = root.Dev
-	case "prod":
-		p = root.Prod
-	default:
-		return nil, fmt.Errorf("unknown env=%q (expected local|dev|prod)", env)
+	if err := merged.Decode(&p); err != nil {
+		te, ok := err.(*yaml.TypeError)
+		if !ok {
+			return nil, err
+		}
+		// типы уже проверены в inspect; если он ничего не нашёл — отдаём как есть
+		if len(problems) == 0 {
+			for _, msg := range te.Errors {
+				problems = append(problems, Problem{Path: "(yaml)", Message: msg})
+			}
+		}
 	}
 	p.Env = env
 
-	if isProxyEmpty(p.Proxy) && !isProxyEmpty(root.Proxy) {
-		p.Proxy = root.Proxy
+	// источник значения — самый "верхний" слой, где задан ключ
+	for _, l := range leaves(reflect.ValueOf(&p).Elem(), "") {
+		for i := len(layers) - 1; i >= 0; i-- {
+			yp := joinPath(layers[i].prefix, l.path)
+			if line, ok := lines[yp]; ok {
+				p.setOrigin(l.path, yp, line)
+				break
+			}
+		}
+	}
+	// элементы списков структур (cli.sinks[0].path) в leaves не попадают
+	for i := len(layers) - 1; i >= 0; i-- {
+		for yp, line := range lines {
+			path, ok := strings.CutPrefix(yp, layers[i].prefix+".")
+			if _, seen := p.origins[path]; ok && !seen && strings.Contains(path, "[") {
+				p.setOrigin(path, yp, line)
+			}
+		}
+	}
+
+	if err := applyEnv(&p, opts.Environ); err != nil {
+		return nil, err
+	}
+	if err := opts.Overrides.apply(&p); err != nil {
+		return nil, err
 	}
 
 	applyDefaults(&p)
-	return &p, nil
-}
 
-func isProxyEmpty(px ProxyConfig) bool {
-	return strings.TrimSpace(px.Mode) == "" && len(px.List) == 0 && strings.TrimSpace(px.RotationURL) == ""
+	if err := p.Validate(); err != nil {
+		problems = append(problems, err.(*ValidationError).Problems...)
+	}
+	if len(problems) > 0 {
+		sortProblems(problems)
+		return nil, &ValidationError{File: path, Problems: problems}
+	}
+	return &p, nil
 }
 
 func applyDefaults(p *Config) {
@@ -115,24 +287,45 @@ func applyDefaults(p *Config) {
 		p.Server.Port = 7891
 	}
 
-	if p.Pagination.PerPage <= 0 {
+	// только незаданные (нулевые) значения; некорректные ловит Validate
+	if p.Pagination.PerPage == 0 {
 		p.Pagination.PerPage = 5
 	}
-	if p.Pagination.PerPage > 5 {
-		p.Pagination.PerPage = 5
-	}
-	if p.Pagination.OffersLimit <= 0 {
+	if p.Pagination.OffersLimit == 0 {
 		p.Pagination.OffersLimit = 10
 	}
-	if p.Pagination.MaxPages <= 0 {
+	if p.Pagination.MaxPages == 0 {
 		p.Pagination.MaxPages = 500
 	}
 
-	if p.HTTP.TimeoutSeconds <= 0 {
+	if p.HTTP.TimeoutSeconds == 0 {
 		p.HTTP.TimeoutSeconds = 30
 	}
-	if p.HTTP.Retries < 0 {
-		p.HTTP.Retries = 0
+
+	tr := &p.HTTP.Transport
+	if tr.DialTimeoutSeconds == 0 {
+		tr.DialTimeoutSeconds = 10
+	}
+	if tr.KeepAliveSeconds == 0 {
+		tr.KeepAliveSeconds = 30
+	}
+	if tr.TLSHandshakeTimeoutSeconds == 0 {
+		tr.TLSHandshakeTimeoutSeconds = 10
+	}
+	if tr.ResponseHeaderTimeoutSeconds == 0 {
+		tr.ResponseHeaderTimeoutSeconds = 15
+	}
+	if tr.ExpectContinueTimeoutSeconds == 0 {
+		tr.ExpectContinueTimeoutSeconds = 1
+	}
+	if tr.IdleConnTimeoutSeconds == 0 {
+		tr.IdleConnTimeoutSeconds = 90
+	}
+	if tr.MaxIdleConns == 0 {
+		tr.MaxIdleConns = 100
+	}
+	if tr.MaxIdleConnsPerHost == 0 {
+		tr.MaxIdleConnsPerHost = 20
 	}
 
 	if p.Log.Level == "" {
@@ -166,7 +359,13 @@ func applyDefaults(p *Config) {
 		p.Proxy.List = clean
 	}
 
-	if p.Proxy.RotationTTLSeconds <= 0 {
+	if p.Proxy.RotationTTLSeconds == 0 {
 		p.Proxy.RotationTTLSeconds = 10
 	}
+	if p.Proxy.EjectAfter == 0 {
+		p.Proxy.EjectAfter = 3
+	}
+	if p.Proxy.EjectSeconds == 0 {
+		p.Proxy.EjectSeconds = 60
+	}
 }
@@ -0,0 +1,271 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Слои конфига (каждый следующий перекрывает предыдущий):
+//
+//	defaults → config.yaml → env KUPERPARSER_* → флаги -section.key
+//
+// Имя env/флага выводится из yaml-пути поля: http.retries →
+// KUPERPARSER_HTTP_RETRIES и -http.retries. Списки в env/флагах — через запятую.
+
+const EnvPrefix = "KUPERPARSER"
+
+const DefaultPath = "./config/config.yaml"
+
+// EnvName возвращает имя переменной окружения для yaml-пути поля.
+func EnvName(path string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(path))
+}
+
+// leaf — конечное поле конфига с yaml-путём.
+type leaf struct {
+	path  string
+	field reflect.StructField
+	value reflect.Value
+}
+
+// leaves перечисляет все конечные поля (string/int/bool/[]string) структуры.
+func leaves(v reflect.Value, prefix string) []leaf {
+	var out []leaf
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			out = append(out, leaves(fv, path)...)
+			continue
+		}
+		// списки структур (cli.sinks) задаются только в yaml
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Struct {
+			continue
+		}
+		out = append(out, leaf{path: path, field: sf, value: fv})
+	}
+	return out
+}
+
+func setLeaf(fv reflect.Value, raw string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected integer, got %q", raw)
+		}
+		fv.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("expected bool, got %q", raw)
+		}
+		fv.SetBool(b)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", fv.Type())
+		}
+		var items []string
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				items = append(items, s)
+			}
+		}
+		fv.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+func lookupEnv(environ []string, key string) string {
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// applyEnv применяет KUPERPARSER_* переменные из environ (формат KEY=VALUE).
+func applyEnv(p *Config, environ []string) error {
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+
+	for _, l := range leaves(reflect.ValueOf(p).Elem(), "") {
+		raw, ok := env[EnvName(l.path)]
+		if !ok {
+			continue
+		}
+		if err := setLeaf(l.value, raw); err != nil {
+			return fmt.Errorf("env %s: %w", EnvName(l.path), err)
+		}
+		p.setOrigin(l.path, "env "+EnvName(l.path), 0)
+	}
+	return nil
+}
+
+// Overrides — значения, заданные флагами, по yaml-пути поля.
+type Overrides struct {
+	values map[string]string
+}
+
+func NewOverrides() *Overrides {
+	return &Overrides{values: make(map[string]string)}
+}
+
+// Set задаёт значение поля по yaml-пути (например "server.port").
+func (o *Overrides) Set(path, value string) {
+	o.values[path] = value
+}
+
+func (o *Overrides) apply(p *Config) error {
+	if o == nil || len(o.values) == 0 {
+		return nil
+	}
+
+	byPath := make(map[string]leaf)
+	for _, l := range leaves(reflect.ValueOf(p).Elem(), "") {
+		byPath[l.path] = l
+	}
+
+	paths := make([]string, 0, len(o.values))
+	for path := range o.values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		l, ok := byPath[path]
+		if !ok {
+			return fmt.Errorf("flag -%s: unknown config field", path)
+		}
+		if err := setLeaf(l.value, o.values[path]); err != nil {
+			return fmt.Errorf("flag -%s: %w", path, err)
+		}
+		p.setOrigin(path, "flag -"+path, 0)
+	}
+	return nil
+}
+
+// overrideFlag — flag.Value, который пишет в Overrides.
+type overrideFlag struct {
+	o      *Overrides
+	path   string
+	isBool bool
+}
+
+func (f *overrideFlag) String() string {
+	if f.o == nil {
+		return ""
+	}
+	return f.o.values[f.path]
+}
+
+func (f *overrideFlag) Set(s string) error {
+	f.o.Set(f.path, s)
+	return nil
+}
+
+func (f *overrideFlag) IsBoolFlag() bool { return f.isBool }
+
+// Flags — общие для всех бинарников флаги конфига.
+type Flags struct {
+	Path        string
+	Env         string
+	PrintConfig bool
+
+	overrides *Overrides
+}
+
+// BindFlags регистрирует -config, -env, -print-config и по флагу на каждое поле
+// конфига (-http.retries, -proxy.list, ...).
+func BindFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{Path: DefaultPath, overrides: NewOverrides()}
+	if v := os.Getenv(EnvPrefix + "_CONFIG"); v != "" {
+		f.Path = v
+	}
+	f.Bind(fs)
+	return f
+}
+
+// Bind регистрирует те же флаги ещё в одном FlagSet (например, у подкоманды):
+// значения общие, уже разобранные не сбрасываются.
+func (f *Flags) Bind(fs *flag.FlagSet) {
+	fs.StringVar(&f.Path, "config", f.Path, "path to config.yaml (env "+EnvPrefix+"_CONFIG)")
+	fs.StringVar(&f.Env, "env", f.Env, "config profile: local|dev|prod|<profiles.name> (env "+EnvPrefix+"_ENV)")
+	fs.BoolVar(&f.PrintConfig, "print-config", f.PrintConfig, "print effective config (secrets redacted) and exit")
+
+	var zero Config
+	for _, l := range leaves(reflect.ValueOf(&zero).Elem(), "") {
+		fs.Var(&overrideFlag{
+			o:      f.overrides,
+			path:   l.path,
+			isBool: l.value.Kind() == reflect.Bool,
+		}, l.path, "override "+l.path+" (env "+EnvName(l.path)+")")
+	}
+}
+
+// Alias регистрирует короткий флаг-синоним для поля конфига (например -port).
+func (f *Flags) Alias(fs *flag.FlagSet, name, path, usage string) {
+	var zero Config
+	isBool := false
+	for _, l := range leaves(reflect.ValueOf(&zero).Elem(), "") {
+		if l.path == path {
+			isBool = l.value.Kind() == reflect.Bool
+		}
+	}
+	fs.Var(&overrideFlag{o: f.overrides, path: path, isBool: isBool}, name, usage)
+}
+
+// Load загружает конфиг со всеми слоями: yaml, env, флаги.
+func (f *Flags) Load() (*Config, error) {
+	return LoadWith(f.Path, LoadOptions{
+		Env:       f.Env,
+		Environ:   os.Environ(),
+		Overrides: f.overrides,
+	})
+}
+
+// Profile — имя профиля, который выберет Load, даже если загрузка падает
+// (для сообщений об ошибке). Нечитаемый файл — без ключа env.
+func (f *Flags) Profile() string {
+	want := f.Env
+	if want == "" {
+		want = lookupEnv(os.Environ(), EnvPrefix+"_ENV")
+	}
+	var doc yaml.Node
+	if b, err := os.ReadFile(f.Path); err == nil {
+		_ = yaml.Unmarshal(b, &doc)
+	}
+	return profileName(rootMapping(&doc), want)
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+
+	"kuperparser/internal/redact"
+)
+
+// Поля с тегом redact скрываются при выводе конфига и в логах:
+//
+//	redact:"url"    — пароль в userinfo и секретные query-параметры;
+//	redact:"strict" — пароль и все query-параметры;
+//	redact:"full"   — значение целиком.
+
+// Redacted возвращает копию конфига со скрытыми секретами.
+func Redacted(p *Config) *Config {
+	cp := *p
+	redactFields(reflect.ValueOf(&cp).Elem())
+	return &cp
+}
+
+func redactFields(v reflect.Value) {
+	for _, l := range leaves(v, "") {
+		mode := l.field.Tag.Get("redact")
+		if mode == "" {
+			continue
+		}
+		switch l.value.Kind() {
+		case reflect.String:
+			l.value.SetString(redactValue(mode, l.value.String()))
+		case reflect.Slice:
+			src, _ := l.value.Interface().([]string)
+			dst := make([]string, len(src))
+			for i, s := range src {
+				dst[i] = redactValue(mode, s)
+			}
+			l.value.Set(reflect.ValueOf(dst))
+		}
+	}
+
+	// списки структур (cli.sinks) leaves пропускает: копируем, чтобы
+	// не тронуть исходный конфиг, и скрываем поэлементно
+	eachStructSlice(v, func(s reflect.Value) {
+		cp := reflect.MakeSlice(s.Type(), s.Len(), s.Len())
+		reflect.Copy(cp, s)
+		for i := 0; i < cp.Len(); i++ {
+			redactFields(cp.Index(i))
+		}
+		s.Set(cp)
+	})
+}
+
+func eachStructSlice(v reflect.Value, fn func(s reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Type().Field(i).IsExported() {
+			continue
+		}
+		switch f := v.Field(i); {
+		case f.Kind() == reflect.Struct:
+			eachStructSlice(f, fn)
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Struct:
+			fn(f)
+		}
+	}
+}
+
+func redactValue(mode, s string) string {
+	if s == "" {
+		return s
+	}
+	switch mode {
+	case "url":
+		return redact.URL(s)
+	case "strict":
+		return redact.URLStrict(s)
+	default:
+		return redact.Mask
+	}
+}
+
+// Print выводит итоговый конфиг в yaml со скрытыми секретами.
+func Print(w io.Writer, p *Config) error {
+	out := struct {
+		Env     string `yaml:"env"`
+		*Config `yaml:",inline"`
+	}{Env: p.Env, Config: Redacted(p)}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	return enc.Close()
+}
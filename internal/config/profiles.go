@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Профиль собирается из слоёв (каждый следующий перекрывает предыдущий, мапы
+// сливаются рекурсивно, списки и скаляры заменяются целиком):
+//
+//	proxy (верхнего уровня) → defaults → local|dev|prod|profiles.<name>
+//
+// Выбор профиля: флаг -env → env KUPERPARSER_ENV → ключ env в yaml → local.
+
+var builtinProfiles = []string{"local", "dev", "prod"}
+
+// layer — кусок yaml-дерева, который вливается в профиль.
+type layer struct {
+	prefix string // yaml-путь слоя ("defaults", "local", "profiles.staging"; "" для proxy)
+	node   *yaml.Node
+}
+
+func rootMapping(doc *yaml.Node) *yaml.Node {
+	n := doc
+	if n.Kind == yaml.DocumentNode {
+		if len(n.Content) == 0 {
+			return nil
+		}
+		n = n.Content[0]
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	return n
+}
+
+func mapGet(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			v := m.Content[i+1]
+			if v.Kind == yaml.AliasNode {
+				v = v.Alias
+			}
+			return v
+		}
+	}
+	return nil
+}
+
+func wrap(key string, v *yaml.Node) *yaml.Node {
+	return &yaml.Node{
+		Kind: yaml.MappingNode,
+		Tag:  "!!map",
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+			v,
+		},
+	}
+}
+
+// merge сливает src поверх dst, не изменяя исходные узлы.
+func merge(dst, src *yaml.Node) *yaml.Node {
+	if src == nil || (src.Kind == yaml.ScalarNode && src.ShortTag() == "!!null") {
+		return dst
+	}
+	if src.Kind == yaml.AliasNode {
+		src = src.Alias
+	}
+	if dst == nil || dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		return src
+	}
+
+	out := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: src.Line, Column: src.Column}
+	out.Content = append(out.Content, dst.Content...)
+
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		k, v := src.Content[i], src.Content[i+1]
+		replaced := false
+		for j := 0; j+1 < len(out.Content); j += 2 {
+			if out.Content[j].Value == k.Value {
+				out.Content[j+1] = merge(out.Content[j+1], v)
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			out.Content = append(out.Content, k, v)
+		}
+	}
+	return out
+}
+
+// selectProfile определяет имя профиля и слои, из которых он собирается.
+func selectProfile(doc *yaml.Node, want string) (string, []layer, error) {
+	top := rootMapping(doc)
+	env := profileName(top, want)
+
+	var layers []layer
+	if n := mapGet(top, "proxy"); n != nil {
+		layers = append(layers, layer{prefix: "", node: wrap("proxy", n)})
+	}
+	if n := mapGet(top, "defaults"); n != nil {
+		layers = append(layers, layer{prefix: "defaults", node: n})
+	}
+
+	for _, b := range builtinProfiles {
+		if env == b {
+			if n := mapGet(top, env); n != nil {
+				layers = append(layers, layer{prefix: env, node: n})
+			}
+			return env, layers, nil
+		}
+	}
+
+	if n := mapGet(mapGet(top, "profiles"), env); n != nil {
+		layers = append(layers, layer{prefix: "profiles." + env, node: n})
+		return env, layers, nil
+	}
+
+	return "", nil, fmt.Errorf("unknown env=%q (available: %s)", env, strings.Join(profileNames(top), "|"))
+}
+
+// profileName: want (флаг или KUPERPARSER_ENV) → ключ env в yaml → local.
+func profileName(top *yaml.Node, want string) string {
+	env := strings.ToLower(strings.TrimSpace(want))
+	if env == "" {
+		if n := mapGet(top, "env"); n != nil {
+			env = strings.ToLower(strings.TrimSpace(n.Value))
+		}
+	}
+	if env == "" {
+		env = "local"
+	}
+	return env
+}
+
+func profileNames(top *yaml.Node) []string {
+	names := append([]string(nil), builtinProfiles...)
+	if m := mapGet(top, "profiles"); m != nil && m.Kind == yaml.MappingNode {
+		var extra []string
+		for i := 0; i < len(m.Content); i += 2 {
+			extra = append(extra, m.Content[i].Value)
+		}
+		sort.Strings(extra)
+		names = append(names, extra...)
+	}
+	return names
+}
+
+// Profiles возвращает профили, описанные в config.yaml: встроенные, которые
+// присутствуют в файле, и все из секции profiles.
+func Profiles(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	top := rootMapping(&doc)
+
+	var out []string
+	for _, name := range profileNames(top) {
+		if mapGet(top, name) != nil || mapGet(mapGet(top, "profiles"), name) != nil {
+			out = append(out, name)
+		}
+	}
+	return out, nil
+}
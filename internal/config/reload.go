@@ -0,0 +1,97 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Change — изменённое поле конфига (секреты уже скрыты).
+type Change struct {
+	Path string
+	Old  string
+	New  string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, c.Old, c.New)
+}
+
+// Diff сравнивает два конфига по всем полям, включая профиль.
+func Diff(old, cur *Config) []Change {
+	var out []Change
+	if old.Env != cur.Env {
+		out = append(out, Change{Path: "env", Old: old.Env, New: cur.Env})
+	}
+
+	a := leaves(reflect.ValueOf(Redacted(old)).Elem(), "")
+	b := leaves(reflect.ValueOf(Redacted(cur)).Elem(), "")
+	for i := range a {
+		// сравниваем исходные значения, а печатаем скрытые:
+		// иначе не заметим смену пароля прокси
+		ov := leafValue(reflect.ValueOf(old).Elem(), a[i].path)
+		nv := leafValue(reflect.ValueOf(cur).Elem(), b[i].path)
+		if reflect.DeepEqual(ov.Interface(), nv.Interface()) {
+			continue
+		}
+		out = append(out, Change{
+			Path: a[i].path,
+			Old:  fmt.Sprint(a[i].value.Interface()),
+			New:  fmt.Sprint(b[i].value.Interface()),
+		})
+	}
+	return out
+}
+
+func leafValue(v reflect.Value, path string) reflect.Value {
+	for _, l := range leaves(v, "") {
+		if l.path == path {
+			return l.value
+		}
+	}
+	return reflect.Value{}
+}
+
+// Watch раз в interval проверяет mtime и размер файла и шлёт сигнал,
+// когда они меняются. Канал закрывается вместе с ctx.
+func Watch(ctx context.Context, path string, interval time.Duration) <-chan struct{} {
+	ch := make(chan struct{}, 1)
+
+	stat := func() (time.Time, int64) {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return fi.ModTime(), fi.Size()
+	}
+
+	go func() {
+		defer close(ch)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		mod, size := stat()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+
+			m, s := stat()
+			if s < 0 || (m.Equal(mod) && s == size) {
+				continue
+			}
+			mod, size = m, s
+
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return ch
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Значения в config.yaml могут ссылаться на секреты:
+//   - ${NAME}           — подстановка переменной окружения (можно внутри строки);
+//   - file:///abs/path  — всё значение берётся из файла (обрезаются пробелы/переводы строк);
+//   - file://rel/path   — путь относительно каталога с config.yaml.
+
+const filePrefix = "file://"
+
+var envRefRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+func resolveSecrets(n *yaml.Node, baseDir string) error {
+	if n == nil {
+		return nil
+	}
+	if n.Kind == yaml.ScalarNode {
+		v, err := resolveValue(n.Value, baseDir)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+		n.Value = v
+		return nil
+	}
+
+	for i, c := range n.Content {
+		// ключи мапы не трогаем
+		if n.Kind == yaml.MappingNode && i%2 == 0 {
+			continue
+		}
+		if err := resolveSecrets(c, baseDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resolveValue(v, baseDir string) (string, error) {
+	var missing []string
+	v = envRefRe.ReplaceAllStringFunc(v, func(ref string) string {
+		name := envRefRe.FindStringSubmatch(ref)[1]
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return val
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("env %s is not set", strings.Join(missing, ", "))
+	}
+
+	if strings.HasPrefix(v, filePrefix) {
+		path := strings.TrimPrefix(v, filePrefix)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read secret file: %w", err)
+		}
+		v = strings.TrimSpace(string(b))
+	}
+
+	return v, nil
+}
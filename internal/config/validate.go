@@ -0,0 +1,410 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem — одна проблема конфига. Path — yaml-путь (local.http.retries)
+// либо имя env/флага, если значение пришло оттуда; Line — строка в config.yaml.
+type Problem struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", p.Line, p.Path, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// ValidationError содержит все найденные проблемы, а не только первую.
+type ValidationError struct {
+	File     string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid config %s: %d problem(s)", e.File, len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  ")
+		b.WriteString(p.String())
+	}
+	return b.String()
+}
+
+// origin — откуда пришло значение поля.
+type origin struct {
+	path string
+	line int
+}
+
+func (p *Config) setOrigin(fieldPath, path string, line int) {
+	if p.origins == nil {
+		p.origins = make(map[string]origin)
+	}
+	p.origins[fieldPath] = origin{path: path, line: line}
+}
+
+func (p *Config) problem(fieldPath, format string, args ...any) Problem {
+	pr := Problem{Path: fieldPath, Message: fmt.Sprintf(format, args...)}
+	if o, ok := p.origins[fieldPath]; ok {
+		pr.Path = o.path
+		pr.Line = o.line
+	}
+	return pr
+}
+
+// Validate проверяет значения конфига (после применения defaults)
+// и возвращает *ValidationError со всеми проблемами сразу.
+func (p *Config) Validate() error {
+	var out []Problem
+	add := func(fieldPath, format string, args ...any) {
+		out = append(out, p.problem(fieldPath, format, args...))
+	}
+
+	switch strings.ToLower(strings.TrimSpace(p.Log.Level)) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		add("log.level", "unknown level %q (expected debug|info|warn|error)", p.Log.Level)
+	}
+	switch strings.ToLower(strings.TrimSpace(p.Log.Format)) {
+	case "text", "json":
+	default:
+		add("log.format", "unknown format %q (expected text|json)", p.Log.Format)
+	}
+
+	if p.Server.Port < 1 || p.Server.Port > 65535 {
+		add("server.port", "must be between 1 and 65535, got %d", p.Server.Port)
+	}
+
+	if u, err := url.Parse(p.Kuper.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		add("kuper.base_url", "must be an absolute http(s) url, got %q", p.Kuper.BaseURL)
+	}
+	if p.Kuper.StoreID < 0 {
+		add("kuper.store_id", "must be >= 0, got %d", p.Kuper.StoreID)
+	}
+	if p.CLI.CategoryID < 0 {
+		add("cli.category_id", "must be >= 0, got %d", p.CLI.CategoryID)
+	}
+
+	switch strings.ToLower(p.CLI.Format) {
+	case "", "json", "ndjson", "csv", "tsv", "xlsx", "yml", "sql":
+	default:
+		add("cli.format", "unknown format %q (expected json|ndjson|csv|tsv|xlsx|yml|sql)", p.CLI.Format)
+	}
+	for i, s := range p.CLI.Sinks {
+		path := fmt.Sprintf("cli.sinks[%d]", i)
+		switch t := strings.ToLower(s.Type); {
+		case t == "webhook" || (t == "" && s.URL != ""):
+			if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				add(path+".url", "must be an absolute http(s) url")
+			}
+			if s.BatchSize < 0 {
+				add(path+".batch_size", "must be >= 0, got %d", s.BatchSize)
+			}
+		case t == "", t == "json", t == "ndjson", t == "csv", t == "tsv", t == "xlsx", t == "yml", t == "sql":
+			if strings.TrimSpace(s.Path) == "" {
+				add(path+".path", "must not be empty")
+			}
+		default:
+			add(path+".type", "unknown type %q (expected json|ndjson|csv|tsv|xlsx|yml|sql|webhook)", s.Type)
+		}
+		switch s.OnError {
+		case "", "fail", "warn":
+		default:
+			add(path+".on_error", "unknown policy %q (expected fail|warn)", s.OnError)
+		}
+	}
+	switch p.CLI.CSV.Delimiter {
+	case "", ",", ";", "tab", "\t", "|":
+	default:
+		add("cli.csv.delimiter", "unsupported delimiter %q (expected , ; | or tab)", p.CLI.CSV.Delimiter)
+	}
+
+	if p.CLI.JSON.Backups < 0 {
+		add("cli.json.backups", "must be >= 0, got %d", p.CLI.JSON.Backups)
+	}
+	switch p.CLI.SQL.Dialect {
+	case "", "postgres", "sqlite":
+	default:
+		add("cli.sql.dialect", "unknown dialect %q (expected postgres|sqlite)", p.CLI.SQL.Dialect)
+	}
+	if p.CLI.SQL.BatchSize < 0 {
+		add("cli.sql.batch_size", "must be >= 0, got %d", p.CLI.SQL.BatchSize)
+	}
+
+	for i, r := range p.Alerts.Rules {
+		path := fmt.Sprintf("alerts.rules[%d]", i)
+		if r.Pattern != "" {
+			if _, err := regexp.Compile(r.Pattern); err != nil {
+				add(path+".pattern", "bad regexp: %v", err)
+			}
+		}
+		if r.ChangePercent < 0 {
+			add(path+".change_percent", "must be >= 0, got %v", r.ChangePercent)
+		}
+		if r.PriceBelow < 0 {
+			add(path+".price_below", "must be >= 0, got %v", r.PriceBelow)
+		}
+		if r.ChangePercent == 0 && r.PriceBelow == 0 && !r.BackInStock {
+			add(path, "no condition (set change_percent, price_below or back_in_stock)")
+		}
+		switch r.Direction {
+		case "", "any", "up", "down":
+		default:
+			add(path+".direction", "unknown direction %q (expected down|up|any)", r.Direction)
+		}
+	}
+	for i, n := range p.Alerts.Notifiers {
+		path := fmt.Sprintf("alerts.notifiers[%d]", i)
+		switch n.Type {
+		case "stdout":
+		case "file":
+			if strings.TrimSpace(n.Path) == "" {
+				add(path+".path", "must not be empty")
+			}
+		case "webhook":
+			if u, err := url.Parse(n.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				add(path+".url", "must be an absolute http(s) url")
+			}
+		default:
+			add(path+".type", "unknown type %q (expected stdout|file|webhook)", n.Type)
+		}
+	}
+
+	if p.Pagination.PerPage < 1 || p.Pagination.PerPage > 5 {
+		add("pagination.per_page", "must be between 1 and 5 (api limit), got %d", p.Pagination.PerPage)
+	}
+	if p.Pagination.OffersLimit < 1 {
+		add("pagination.offers_limit", "must be > 0, got %d", p.Pagination.OffersLimit)
+	}
+	if p.Pagination.MaxPages < 1 {
+		add("pagination.max_pages", "must be > 0, got %d", p.Pagination.MaxPages)
+	}
+	if p.Pagination.PageTimeoutSeconds < 0 {
+		add("pagination.page_timeout_seconds", "must be >= 0, got %d", p.Pagination.PageTimeoutSeconds)
+	}
+	if p.CLI.JobTimeoutSeconds < 0 {
+		add("cli.job_timeout_seconds", "must be >= 0, got %d", p.CLI.JobTimeoutSeconds)
+	}
+
+	if p.HTTP.TimeoutSeconds < 1 {
+		add("http.timeout_seconds", "must be > 0, got %d", p.HTTP.TimeoutSeconds)
+	}
+	if p.HTTP.Retries < 0 {
+		add("http.retries", "must be >= 0, got %d", p.HTTP.Retries)
+	}
+
+	tr := p.HTTP.Transport
+	for path, v := range map[string]int{
+		"http.transport.dial_timeout_seconds":            tr.DialTimeoutSeconds,
+		"http.transport.keep_alive_seconds":              tr.KeepAliveSeconds,
+		"http.transport.tls_handshake_timeout_seconds":   tr.TLSHandshakeTimeoutSeconds,
+		"http.transport.response_header_timeout_seconds": tr.ResponseHeaderTimeoutSeconds,
+		"http.transport.expect_continue_timeout_seconds": tr.ExpectContinueTimeoutSeconds,
+		"http.transport.idle_conn_timeout_seconds":       tr.IdleConnTimeoutSeconds,
+		"http.transport.max_idle_conns":                  tr.MaxIdleConns,
+		"http.transport.max_idle_conns_per_host":         tr.MaxIdleConnsPerHost,
+		"http.transport.max_conns_per_host":              tr.MaxConnsPerHost,
+	} {
+		if v < 0 {
+			add(path, "must be >= 0, got %d", v)
+		}
+	}
+	switch tr.TLS.MinVersion {
+	case "", "1.0", "1.1", "1.2", "1.3":
+	default:
+		add("http.transport.tls.min_version", "unknown tls version %q (expected 1.0|1.1|1.2|1.3)", tr.TLS.MinVersion)
+	}
+	if tr.TLS.CAFile != "" {
+		if _, err := os.Stat(tr.TLS.CAFile); err != nil {
+			add("http.transport.tls.ca_file", "%v", err)
+		}
+	}
+
+	switch p.Proxy.Mode {
+	case "disabled":
+	case "list":
+		if len(p.Proxy.List) == 0 {
+			add("proxy.list", "must not be empty when proxy.mode=list")
+		}
+	case "rotation":
+		if strings.TrimSpace(p.Proxy.RotationURL) == "" {
+			add("proxy.rotation_url", "must be set when proxy.mode=rotation")
+		}
+	default:
+		add("proxy.mode", "unknown mode %q (expected disabled|list|rotation)", p.Proxy.Mode)
+	}
+	for i, raw := range p.Proxy.List {
+		s := raw
+		if !strings.Contains(s, "://") {
+			s = "http://" + s
+		}
+		if u, err := url.Parse(s); err != nil || u.Host == "" {
+			add("proxy.list", "item %d is not a valid proxy url", i)
+		}
+	}
+	if p.Proxy.RotationURL != "" {
+		if u, err := url.Parse(p.Proxy.RotationURL); err != nil || u.Host == "" {
+			add("proxy.rotation_url", "must be an absolute url")
+		}
+	}
+	if p.Proxy.RotationTTLSeconds < 0 {
+		add("proxy.rotation_ttl_seconds", "must be >= 0, got %d", p.Proxy.RotationTTLSeconds)
+	}
+	if p.Proxy.EjectAfter < 0 {
+		add("proxy.eject_after", "must be >= 0, got %d", p.Proxy.EjectAfter)
+	}
+	if p.Proxy.EjectSeconds < 0 {
+		add("proxy.eject_seconds", "must be >= 0, got %d", p.Proxy.EjectSeconds)
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: out}
+}
+
+// inspect проходит yaml-дерево вместе с типом, в который оно декодируется:
+// собирает неизвестные ключи, несовпадения типов и номера строк значений.
+func inspect(n *yaml.Node, t reflect.Type, path string, lines map[string]int) []Problem {
+	if n == nil || n.Kind == 0 {
+		return nil
+	}
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil
+		}
+		return inspect(n.Content[0], t, path, lines)
+	case yaml.AliasNode:
+		return inspect(n.Alias, t, path, lines)
+	}
+
+	if n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null" {
+		return nil
+	}
+	lines[path] = n.Line
+
+	typeErr := func(want string) []Problem {
+		return []Problem{{Path: path, Line: n.Line, Message: fmt.Sprintf("expected %s, got %q", want, n.Value)}}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return []Problem{{Path: path, Line: n.Line, Message: "expected a mapping"}}
+		}
+		fields := yamlFields(t)
+		var out []Problem
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.Value == "<<" {
+				continue
+			}
+			kp := joinPath(path, k.Value)
+			ft, ok := fields[k.Value]
+			if !ok {
+				out = append(out, Problem{Path: kp, Line: k.Line, Message: "unknown key"})
+				continue
+			}
+			out = append(out, inspect(v, ft, kp, lines)...)
+		}
+		return out
+
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return []Problem{{Path: path, Line: n.Line, Message: "expected a mapping"}}
+		}
+		var out []Problem
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			out = append(out, inspect(n.Content[i+1], t.Elem(), joinPath(path, n.Content[i].Value), lines)...)
+		}
+		return out
+
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			return []Problem{{Path: path, Line: n.Line, Message: "expected a list"}}
+		}
+		var out []Problem
+		for i, c := range n.Content {
+			out = append(out, inspect(c, t.Elem(), fmt.Sprintf("%s[%d]", path, i), lines)...)
+		}
+		return out
+
+	case reflect.Int, reflect.Int64:
+		if n.Kind != yaml.ScalarNode || n.ShortTag() != "!!int" {
+			return typeErr("integer")
+		}
+	case reflect.Float64:
+		if n.Kind != yaml.ScalarNode || (n.ShortTag() != "!!float" && n.ShortTag() != "!!int") {
+			return typeErr("number")
+		}
+	case reflect.Bool:
+		if n.Kind != yaml.ScalarNode || n.ShortTag() != "!!bool" {
+			return typeErr("bool")
+		}
+	case reflect.String:
+		if n.Kind != yaml.ScalarNode {
+			return []Problem{{Path: path, Line: n.Line, Message: "expected a string"}}
+		}
+	}
+	return nil
+}
+
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	out := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		parts := strings.Split(sf.Tag.Get("yaml"), ",")
+		if parts[0] == "-" {
+			continue
+		}
+		if len(parts) > 1 && parts[1] == "inline" {
+			for k, v := range yamlFields(sf.Type) {
+				out[k] = v
+			}
+			continue
+		}
+		name := parts[0]
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		out[name] = sf.Type
+	}
+	return out
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func sortProblems(ps []Problem) {
+	sort.SliceStable(ps, func(i, j int) bool {
+		if ps[i].Line != ps[j].Line {
+			// проблемы без строки (env/флаги) — в конце
+			if ps[i].Line == 0 || ps[j].Line == 0 {
+				return ps[j].Line == 0
+			}
+			return ps[i].Line < ps[j].Line
+		}
+		return ps[i].Path < ps[j].Path
+	})
+}
@@ -0,0 +1,160 @@
+package diff
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"kuperparser/internal/domain/models"
+	"kuperparser/internal/repository"
+)
+
+// Сравнение двух выгрузок. Товары сопоставляются по url (без url — по имени),
+// магазины — по id. Порядок в отчёте — как в исходных файлах.
+
+const (
+	KindCategory = "category"
+	KindStores   = "stores"
+)
+
+type Side struct {
+	FetchedAt string `json:"fetched_at"`
+	Count     int    `json:"count"`
+}
+
+type PriceChange struct {
+	URL      string `json:"url"`
+	Name     string `json:"name"`
+	OldPrice string `json:"old_price"`
+	NewPrice string `json:"new_price"`
+	// nil, если цена не число
+	Delta *float64 `json:"delta,omitempty"`
+	// nil, если старая цена 0 или не число
+	Percent *float64 `json:"percent,omitempty"`
+}
+
+type Rename struct {
+	URL     string `json:"url"`
+	OldName string `json:"old_name"`
+	NewName string `json:"new_name"`
+}
+
+type Report struct {
+	Kind string `json:"kind"` // category|stores
+	Old  Side   `json:"old"`
+	New  Side   `json:"new"`
+
+	Added        []models.Product `json:"added,omitempty"`
+	Removed      []models.Product `json:"removed,omitempty"`
+	PriceChanges []PriceChange    `json:"price_changes,omitempty"`
+	Renamed      []Rename         `json:"renamed,omitempty"`
+
+	NewStores    []repository.StoreMeta `json:"new_stores,omitempty"`
+	ClosedStores []repository.StoreMeta `json:"closed_stores,omitempty"`
+}
+
+// Empty — изменений нет.
+func (r Report) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.PriceChanges) == 0 &&
+		len(r.Renamed) == 0 && len(r.NewStores) == 0 && len(r.ClosedStores) == 0
+}
+
+// Categories сравнивает два результата категории.
+func Categories(old, cur repository.CategoryResult) Report {
+	r := Report{
+		Kind: KindCategory,
+		Old:  Side{FetchedAt: old.FetchedAt, Count: len(old.Products)},
+		New:  Side{FetchedAt: cur.FetchedAt, Count: len(cur.Products)},
+	}
+
+	before := make(map[string]models.Product, len(old.Products))
+	for _, p := range old.Products {
+		if _, dup := before[p.Key()]; !dup {
+			before[p.Key()] = p
+		}
+	}
+
+	seen := make(map[string]bool, len(cur.Products))
+	for _, p := range cur.Products {
+		k := p.Key()
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		prev, ok := before[k]
+		if !ok {
+			r.Added = append(r.Added, p)
+			continue
+		}
+		if p.URL != "" && prev.Name != p.Name {
+			r.Renamed = append(r.Renamed, Rename{URL: p.URL, OldName: prev.Name, NewName: p.Name})
+		}
+		if prev.Price != p.Price {
+			r.PriceChanges = append(r.PriceChanges, priceChange(prev, p))
+		}
+	}
+
+	removed := make(map[string]bool)
+	for _, p := range old.Products {
+		k := p.Key()
+		if !seen[k] && !removed[k] {
+			removed[k] = true
+			r.Removed = append(r.Removed, p)
+		}
+	}
+	return r
+}
+
+// Stores сравнивает два результата сканирования магазинов.
+func Stores(old, cur repository.StoresResult) Report {
+	r := Report{
+		Kind: KindStores,
+		Old:  Side{FetchedAt: old.FetchedAt, Count: len(old.Stores)},
+		New:  Side{FetchedAt: cur.FetchedAt, Count: len(cur.Stores)},
+	}
+
+	before := make(map[int]bool, len(old.Stores))
+	for _, s := range old.Stores {
+		before[s.ID] = true
+	}
+	after := make(map[int]bool, len(cur.Stores))
+	for _, s := range cur.Stores {
+		if !before[s.ID] && !after[s.ID] {
+			r.NewStores = append(r.NewStores, s)
+		}
+		after[s.ID] = true
+	}
+	for _, s := range old.Stores {
+		if !after[s.ID] {
+			r.ClosedStores = append(r.ClosedStores, s)
+			after[s.ID] = true // дубли в старом файле
+		}
+	}
+	return r
+}
+
+func priceChange(prev, cur models.Product) PriceChange {
+	c := PriceChange{URL: cur.URL, Name: cur.Name, OldPrice: prev.Price, NewPrice: cur.Price}
+
+	o, err1 := strconv.ParseFloat(prev.Price, 64)
+	n, err2 := strconv.ParseFloat(cur.Price, 64)
+	if err1 != nil || err2 != nil {
+		return c
+	}
+	d := round2(n - o)
+	c.Delta = &d
+	if o != 0 {
+		p := round2((n - o) / o * 100)
+		c.Percent = &p
+	}
+	return c
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func signed(v float64) string {
+	return fmt.Sprintf("%+.2f", v)
+}
@@ -0,0 +1,154 @@
+package diff
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Formats — форматы отчёта для Write.
+var Formats = []string{"text", "json", "csv"}
+
+func Write(w io.Writer, format string, r Report) error {
+	switch strings.ToLower(format) {
+	case "", "text":
+		return WriteText(w, r)
+	case "json":
+		return WriteJSON(w, r)
+	case "csv":
+		return WriteCSV(w, r)
+	default:
+		return fmt.Errorf("unknown diff format %q (expected %s)", format, strings.Join(Formats, "|"))
+	}
+}
+
+func WriteJSON(w io.Writer, r Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText — отчёт для человека: сводка и списки по секциям.
+func WriteText(w io.Writer, r Report) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	unit := "products"
+	if r.Kind == KindStores {
+		unit = "stores"
+	}
+	fmt.Fprintf(tw, "old:\t%s\t(%d %s)\n", r.Old.FetchedAt, r.Old.Count, unit)
+	fmt.Fprintf(tw, "new:\t%s\t(%d %s)\n", r.New.FetchedAt, r.New.Count, unit)
+
+	if r.Empty() {
+		fmt.Fprintln(tw, "\nno changes")
+		return tw.Flush()
+	}
+
+	if len(r.Added) > 0 {
+		fmt.Fprintf(tw, "\nadded: %d\n", len(r.Added))
+		for _, p := range r.Added {
+			fmt.Fprintf(tw, "  +\t%s\t%s\t%s\n", p.Name, p.Price, p.URL)
+		}
+	}
+	if len(r.Removed) > 0 {
+		fmt.Fprintf(tw, "\nremoved: %d\n", len(r.Removed))
+		for _, p := range r.Removed {
+			fmt.Fprintf(tw, "  -\t%s\t%s\t%s\n", p.Name, p.Price, p.URL)
+		}
+	}
+	if len(r.PriceChanges) > 0 {
+		fmt.Fprintf(tw, "\nprice changes: %d\n", len(r.PriceChanges))
+		for _, c := range r.PriceChanges {
+			change := ""
+			if c.Delta != nil {
+				change = signed(*c.Delta)
+			}
+			if c.Percent != nil {
+				change += " (" + signed(*c.Percent) + "%)"
+			}
+			fmt.Fprintf(tw, "  ~\t%s\t%s → %s\t%s\t%s\n", c.Name, c.OldPrice, c.NewPrice, change, c.URL)
+		}
+	}
+	if len(r.Renamed) > 0 {
+		fmt.Fprintf(tw, "\nrenamed: %d\n", len(r.Renamed))
+		for _, c := range r.Renamed {
+			fmt.Fprintf(tw, "  *\t%q → %q\t%s\n", c.OldName, c.NewName, c.URL)
+		}
+	}
+	if len(r.NewStores) > 0 {
+		fmt.Fprintf(tw, "\nnew stores: %d\n", len(r.NewStores))
+		for _, s := range r.NewStores {
+			fmt.Fprintf(tw, "  +\t%d\t%s\t%s\n", s.ID, s.Name, s.Address)
+		}
+	}
+	if len(r.ClosedStores) > 0 {
+		fmt.Fprintf(tw, "\nclosed stores: %d\n", len(r.ClosedStores))
+		for _, s := range r.ClosedStores {
+			fmt.Fprintf(tw, "  -\t%d\t%s\t%s\n", s.ID, s.Name, s.Address)
+		}
+	}
+	return tw.Flush()
+}
+
+var csvHeader = []string{
+	"change", "url", "name", "old_name", "old_price", "new_price", "delta", "percent",
+	"store_id", "store_name", "store_address",
+}
+
+// WriteCSV — строка на изменение; change: added|removed|price|renamed|store_added|store_closed.
+func WriteCSV(w io.Writer, r Report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	row := func(change string) []string {
+		out := make([]string, len(csvHeader))
+		out[0] = change
+		return out
+	}
+
+	for _, p := range r.Added {
+		rec := row("added")
+		rec[1], rec[2], rec[5] = p.URL, p.Name, p.Price
+		_ = cw.Write(rec)
+	}
+	for _, p := range r.Removed {
+		rec := row("removed")
+		rec[1], rec[2], rec[4] = p.URL, p.Name, p.Price
+		_ = cw.Write(rec)
+	}
+	for _, c := range r.PriceChanges {
+		rec := row("price")
+		rec[1], rec[2], rec[4], rec[5] = c.URL, c.Name, c.OldPrice, c.NewPrice
+		if c.Delta != nil {
+			rec[6] = strconv.FormatFloat(*c.Delta, 'f', 2, 64)
+		}
+		if c.Percent != nil {
+			rec[7] = strconv.FormatFloat(*c.Percent, 'f', 2, 64)
+		}
+		_ = cw.Write(rec)
+	}
+	for _, c := range r.Renamed {
+		rec := row("renamed")
+		rec[1], rec[2], rec[3] = c.URL, c.NewName, c.OldName
+		_ = cw.Write(rec)
+	}
+	for _, s := range r.NewStores {
+		rec := row("store_added")
+		rec[8], rec[9], rec[10] = strconv.Itoa(s.ID), s.Name, s.Address
+		_ = cw.Write(rec)
+	}
+	for _, s := range r.ClosedStores {
+		rec := row("store_closed")
+		rec[8], rec[9], rec[10] = strconv.Itoa(s.ID), s.Name, s.Address
+		_ = cw.Write(rec)
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -5,3 +5,11 @@ type Product struct {
 	Price string `json:"price"`
 	URL   string `json:"url"`
 }
+
+// Key — ключ товара: сопоставление между выгрузками и дедупликация в каталоге.
+func (p Product) Key() string {
+	if p.URL != "" {
+		return p.URL
+	}
+	return "name:" + p.Name
+}
@@ -75,7 +75,7 @@ func NewGetHandler(opts Options) http.HandlerFunc {
 			return
 		}
 
-		flat := flatten(tree, 0, opts.HideRoofLeaf)
+		flat := Flatten(tree, opts.HideRoofLeaf)
 
 		respond.WriteJSON(w, 200, map[string]any{
 			"store_id":   storeID,
@@ -85,6 +85,12 @@ func NewGetHandler(opts Options) http.HandlerFunc {
 		})
 	}
 }
+
+// Flatten — дерево категорий плоским списком в порядке обхода, с уровнем вложенности.
+func Flatten(cats []kuper.Category, hideRootLeaf bool) []FlatCategory {
+	return flatten(cats, 0, hideRootLeaf)
+}
+
 func flatten(cats []kuper.Category, level int, hideRootLeaf bool) []FlatCategory {
 	out := make([]FlatCategory, 0, 256)
 
@@ -0,0 +1,138 @@
+package history
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"kuperparser/internal/http-server/query"
+	"kuperparser/internal/http-server/respond"
+	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/history"
+)
+
+type Reader interface {
+	Timeline(storeID int, url string, from, to time.Time) ([]history.PricePoint, error)
+	Snapshot(storeID, categoryID int, at time.Time) (repository.CategoryResult, error)
+}
+
+type Options struct {
+	Log            *slog.Logger
+	History        Reader
+	DefaultStoreID int
+}
+
+// NewPricesHandler: GET /history/prices?url=...&storeID=&from=&to=
+// storeID по умолчанию — из конфига, storeID=0 — все магазины.
+func NewPricesHandler(opts Options) http.HandlerFunc {
+	log := opts.Log
+	if log == nil {
+		log = slog.Default()
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			respond.WriteError(w, 405, "method_not_allowed", "GET only")
+			return
+		}
+		if opts.History == nil {
+			log.Error("history handler misconfigured: History is nil")
+			respond.WriteInternalError(w)
+			return
+		}
+
+		url := r.URL.Query().Get("url")
+		if url == "" {
+			respond.WriteError(w, 400, "bad_request", "url is required")
+			return
+		}
+
+		storeID := opts.DefaultStoreID
+		if v, present, err := query.IntAny(r, "storeID", "storeid"); err != nil {
+			respond.WriteError(w, 400, "bad_request", err.Error())
+			return
+		} else if present {
+			storeID = v
+		}
+
+		from, _, err := query.Time(r, "from", false)
+		if err != nil {
+			respond.WriteError(w, 400, "bad_request", err.Error())
+			return
+		}
+		to, _, err := query.Time(r, "to", true)
+		if err != nil {
+			respond.WriteError(w, 400, "bad_request", err.Error())
+			return
+		}
+
+		points, err := opts.History.Timeline(storeID, url, from, to)
+		if err != nil {
+			log.Error("history timeline failed", "err", err, "store_id", storeID)
+			respond.WriteInternalError(w)
+			return
+		}
+
+		respond.WriteJSON(w, 200, map[string]any{
+			"url":    url,
+			"count":  len(points),
+			"prices": points,
+		})
+	}
+}
+
+// NewSnapshotHandler: GET /history/snapshot?categoryID=...&storeID=&at=
+// at по умолчанию — сейчас (последний сохранённый запуск).
+func NewSnapshotHandler(opts Options) http.HandlerFunc {
+	log := opts.Log
+	if log == nil {
+		log = slog.Default()
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			respond.WriteError(w, 405, "method_not_allowed", "GET only")
+			return
+		}
+		if opts.History == nil {
+			log.Error("history handler misconfigured: History is nil")
+			respond.WriteInternalError(w)
+			return
+		}
+
+		storeID := opts.DefaultStoreID
+		if v, present, err := query.IntAny(r, "storeID", "storeid"); err != nil {
+			respond.WriteError(w, 400, "bad_request", err.Error())
+			return
+		} else if present {
+			storeID = v
+		}
+
+		categoryID, present, err := query.IntAny(r, "categoryID", "categoryid")
+		if err != nil {
+			respond.WriteError(w, 400, "bad_request", err.Error())
+			return
+		}
+		if !present {
+			respond.WriteError(w, 400, "bad_request", "categoryID is required")
+			return
+		}
+
+		at, present, err := query.Time(r, "at", true)
+		if err != nil {
+			respond.WriteError(w, 400, "bad_request", err.Error())
+			return
+		}
+		if !present {
+			at = time.Now()
+		}
+
+		res, err := opts.History.Snapshot(storeID, categoryID, at)
+		if err != nil {
+			respond.WriteError(w, http.StatusNotFound, "not_found", err.Error())
+			return
+		}
+
+		respond.WriteJSON(w, 200, res)
+	}
+}
@@ -23,10 +23,16 @@ type StoreGetter interface {
 	GetStore(ctx context.Context, storeID int) (responses.StoreInfo, error)
 }
 
+// HistoryAppender — история цен; результат дописывается после ответа upstream.
+type HistoryAppender interface {
+	Append(ctx context.Context, storeID int, res repository.CategoryResult) error
+}
+
 type Options struct {
 	Log            *slog.Logger
 	Products       ProductsGetter
 	Store          StoreGetter
+	History        HistoryAppender // nil — история не пишется
 	DefaultStoreID int
 	Timeout        time.Duration
 }
@@ -127,6 +133,13 @@ func NewGetHandler(opts Options) http.HandlerFunc {
 			Count:     len(products),
 		}
 
+		if opts.History != nil {
+			// ошибка истории не должна ломать ответ
+			if err := opts.History.Append(ctx, storeID, res); err != nil {
+				log.Warn("history append failed (continue)", "err", err, "store_id", storeID, "category_id", categoryID)
+			}
+		}
+
 		respond.WriteJSON(w, 200, res)
 	}
 }
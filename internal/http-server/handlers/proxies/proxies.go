@@ -0,0 +1,69 @@
+package proxies
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"kuperparser/internal/client/proxy"
+	"kuperparser/internal/http-server/respond"
+)
+
+type StatusLister interface {
+	Snapshot() []proxy.Status
+}
+
+type Options struct {
+	Log    *slog.Logger
+	Health StatusLister
+	Mode   string
+	// Token — ожидаемый Authorization: Bearer <token>; пустой не пускает никого
+	Token string
+}
+
+func NewGetHandler(opts Options) http.HandlerFunc {
+	log := opts.Log
+	if log == nil {
+		log = slog.Default()
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			respond.WriteError(w, 405, "method_not_allowed", "GET only")
+			return
+		}
+		if !authorized(r, opts.Token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			respond.WriteError(w, 401, "unauthorized", "admin token required")
+			return
+		}
+		if opts.Health == nil {
+			log.Error("proxies handler misconfigured: health is nil")
+			respond.WriteInternalError(w)
+			return
+		}
+
+		list := opts.Health.Snapshot()
+		ejected := 0
+		for _, st := range list {
+			if st.Ejected {
+				ejected++
+			}
+		}
+
+		respond.WriteJSON(w, 200, map[string]any{
+			"mode":       opts.Mode,
+			"fetched_at": time.Now().UTC().Format(time.RFC3339),
+			"count":      len(list),
+			"ejected":    ejected,
+			"proxies":    list,
+		})
+	}
+}
+
+func authorized(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 func Int(r *http.Request, key string) (val int, present bool, err error) {
@@ -30,3 +31,31 @@ func IntAny(r *http.Request, keys ...string) (val int, present bool, err error)
 	}
 	return 0, false, nil
 }
+
+// Time разбирает RFC3339 или дату 2006-01-02 (UTC): начало дня, а с endOfDay —
+// его конец (для верхних границ: to=2026-10-01 включает весь день).
+func Time(r *http.Request, key string, endOfDay bool) (val time.Time, present bool, err error) {
+	raw := r.URL.Query().Get(key)
+	if raw == "" {
+		return time.Time{}, false, nil
+	}
+	t, err := ParseTime(raw, endOfDay)
+	if err != nil {
+		return time.Time{}, true, fmt.Errorf("%s must be RFC3339 time or YYYY-MM-DD", key)
+	}
+	return t, true, nil
+}
+
+func ParseTime(raw string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return t, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
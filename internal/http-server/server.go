@@ -2,27 +2,38 @@ package httpserver
 
 import (
 	"kuperparser/internal/http-server/handlers/categories"
+	"kuperparser/internal/http-server/handlers/history"
 	"kuperparser/internal/http-server/handlers/products"
+	"kuperparser/internal/http-server/handlers/proxies"
 	"kuperparser/internal/http-server/middleware"
+	historystore "kuperparser/internal/repository/history"
 	"log/slog"
 	"net/http"
+	"sync/atomic"
 	"time"
 )
 
 type Server struct {
 	log *slog.Logger
-	mux *http.ServeMux
+
+	// mux подменяется целиком при перезагрузке конфига,
+	// запросы в полёте дорабатывают на старом
+	mux atomic.Pointer[http.ServeMux]
 }
 
 func New(log *slog.Logger) *Server {
 	if log == nil {
 		log = slog.Default()
 	}
-	return &Server{log: log, mux: http.NewServeMux()}
+	s := &Server{log: log}
+	s.mux.Store(http.NewServeMux())
+	return s
 }
 
 func (s *Server) Handler() http.Handler {
-	var h http.Handler = s.mux
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mux.Load().ServeHTTP(w, r)
+	})
 	h = middleware.WithRequestID(h)
 	h = middleware.RecoverPanic(s.log, h)
 	h = middleware.AccessLog(s.log, h)
@@ -33,13 +44,22 @@ type Deps struct {
 	Categories     categories.Lister
 	Products       products.ProductsGetter
 	Store          products.StoreGetter
+	History        *historystore.Store // nil — история цен выключена
 	DefaultStoreID int
 	Timeout        time.Duration
+
+	ProxyHealth proxies.StatusLister
+	ProxyMode   string
+	// AdminToken — токен /admin/*; пусто — маршруты не регистрируются
+	AdminToken string
 }
 
+// RegisterRoutes собирает маршруты на новых зависимостях и атомарно
+// подменяет ими текущие; можно вызывать повторно (hot reload).
 func (s *Server) RegisterRoutes(dep Deps) {
+	mux := http.NewServeMux()
 
-	s.mux.HandleFunc("/categories", categories.NewGetHandler(categories.Options{
+	mux.HandleFunc("/categories", categories.NewGetHandler(categories.Options{
 		Log:            s.log,
 		Lister:         dep.Categories,
 		DefaultStoreID: dep.DefaultStoreID,
@@ -47,11 +67,37 @@ func (s *Server) RegisterRoutes(dep Deps) {
 		HideRoofLeaf:   true,
 	}))
 
-	s.mux.HandleFunc("/products", products.NewGetHandler(products.Options{
+	productOpts := products.Options{
 		Log:            s.log,
 		Products:       dep.Products,
 		Store:          dep.Store,
 		DefaultStoreID: dep.DefaultStoreID,
 		Timeout:        dep.Timeout,
-	}))
+	}
+	if dep.History != nil {
+		// интерфейс с nil-указателем внутри не равен nil
+		productOpts.History = dep.History
+	}
+	mux.HandleFunc("/products", products.NewGetHandler(productOpts))
+
+	if dep.History != nil {
+		hopts := history.Options{
+			Log:            s.log,
+			History:        dep.History,
+			DefaultStoreID: dep.DefaultStoreID,
+		}
+		mux.HandleFunc("/history/prices", history.NewPricesHandler(hopts))
+		mux.HandleFunc("/history/snapshot", history.NewSnapshotHandler(hopts))
+	}
+
+	if dep.ProxyHealth != nil && dep.AdminToken != "" {
+		mux.HandleFunc("/admin/proxies", proxies.NewGetHandler(proxies.Options{
+			Log:    s.log,
+			Health: dep.ProxyHealth,
+			Mode:   dep.ProxyMode,
+			Token:  dep.AdminToken,
+		}))
+	}
+
+	s.mux.Store(mux)
 }
@@ -11,10 +11,17 @@ type Options struct {
 	Format    string // text|json
 	AddSource bool
 	Env       string
+
+	// LevelVar (опционально) — уровень можно менять на лету (hot reload).
+	LevelVar *slog.LevelVar
 }
 
 func New(opts Options) *slog.Logger {
-	level := parseLevel(opts.Level)
+	var level slog.Leveler = ParseLevel(opts.Level)
+	if opts.LevelVar != nil {
+		opts.LevelVar.Set(ParseLevel(opts.Level))
+		level = opts.LevelVar
+	}
 
 	hopts := &slog.HandlerOptions{
 		Level:     level,
@@ -39,7 +46,7 @@ func New(opts Options) *slog.Logger {
 	return l
 }
 
-func parseLevel(s string) slog.Level {
+func ParseLevel(s string) slog.Level {
 	switch strings.ToLower(strings.TrimSpace(s)) {
 	case "debug":
 		return slog.LevelDebug
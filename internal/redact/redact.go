@@ -0,0 +1,135 @@
+package redact
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const Mask = "***"
+
+// sensitiveKeys — query-параметры, значения которых считаются секретом.
+var sensitiveKeys = []string{"token", "key", "secret", "pass", "auth", "sig", "session", "credential"}
+
+func isSensitive(k string) bool {
+	k = strings.ToLower(k)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// URL скрывает пароль из userinfo и значения "секретных" query-параметров
+// (token, api_key, sig, ...). Строка без схемы трактуется как host:port.
+func URL(raw string) string {
+	return redactURL(raw, isSensitive, false)
+}
+
+// URLStrict — для адресов, которые целиком являются секретом (rotation_url):
+// остаются схема и хост, а userinfo, путь (токен бывает в нём) и значения
+// всех query-параметров скрываются.
+func URLStrict(raw string) string {
+	return redactURL(raw, func(string) bool { return true }, true)
+}
+
+func redactURL(raw string, hide func(key string) bool, strict bool) string {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return raw
+	}
+
+	noScheme := !strings.Contains(s, "://")
+	if noScheme {
+		s = "http://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		// не парсится — лучше не показывать вовсе
+		return Mask
+	}
+
+	var b strings.Builder
+	if !noScheme {
+		b.WriteString(u.Scheme)
+		b.WriteString("://")
+	}
+	switch {
+	case u.User != nil && strict:
+		b.WriteString(Mask + "@")
+	case u.User != nil:
+		b.WriteString(u.User.Username())
+		if _, ok := u.User.Password(); ok {
+			b.WriteString(":")
+			b.WriteString(Mask)
+		}
+		b.WriteString("@")
+	}
+	b.WriteString(u.Host)
+	switch path := u.EscapedPath(); {
+	case strict && path != "" && path != "/":
+		b.WriteString("/" + Mask)
+	default:
+		b.WriteString(path)
+	}
+
+	if u.RawQuery != "" {
+		q := u.Query()
+		keys := make([]string, 0, len(q))
+		for k := range q {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			if i == 0 {
+				b.WriteString("?")
+			} else {
+				b.WriteString("&")
+			}
+			if !hide(k) {
+				for j, v := range q[k] {
+					if j > 0 {
+						b.WriteString("&")
+					}
+					b.WriteString(url.QueryEscape(k))
+					b.WriteString("=")
+					b.WriteString(url.QueryEscape(v))
+				}
+				continue
+			}
+			b.WriteString(url.QueryEscape(k))
+			b.WriteString("=")
+			b.WriteString(Mask)
+		}
+	}
+
+	return b.String()
+}
+
+var urlRe = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+
+// Text прогоняет через URL все ссылки, встреченные в произвольном тексте
+// (например, в тексте ошибки net/http).
+func Text(s string) string {
+	return urlRe.ReplaceAllStringFunc(s, URL)
+}
+
+// Error — то же, что Text, для ошибок; nil остаётся пустой строкой.
+func Error(err error) string {
+	if err == nil {
+		return ""
+	}
+	return Text(err.Error())
+}
+
+// URLs — URL для каждого элемента списка.
+func URLs(list []string) []string {
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = URL(s)
+	}
+	return out
+}
@@ -0,0 +1,220 @@
+package atomicfile
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Файл пишется во временный файл с уникальным именем рядом с целевым и
+// публикуется rename-ом, поэтому читатель никогда не видит наполовину
+// записанный результат, а параллельные писатели не портят временные файлы
+// друг друга. Перед rename файл fsync-ается, после — каталог.
+
+type Options struct {
+	// Lock — advisory-блокировка path+".lock" на время записи (flock на unix):
+	// параллельные процессы пишут по очереди, а не «последний победил».
+	// Файл блокировки удаляется после записи.
+	Lock bool
+	// Backups — сколько предыдущих версий хранить (path.1 — самая свежая).
+	Backups int
+	// Gzip сжимает содержимое; включается сам, если path оканчивается на .gz.
+	Gzip bool
+}
+
+type File struct {
+	path string
+	opts Options
+
+	f      *os.File
+	zw     *gzip.Writer
+	bw     *bufio.Writer
+	unlock func()
+	done   bool
+}
+
+// Create открывает временный файл для path. Запись публикуется Commit,
+// отменяется Abort.
+func Create(path string, opts Options) (*File, error) {
+	if path == "" {
+		return nil, fmt.Errorf("atomicfile: empty path")
+	}
+	if strings.HasSuffix(strings.ToLower(path), ".gz") {
+		opts.Gzip = true
+	}
+
+	dir := filepath.Dir(path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	unlock := func() {}
+	if opts.Lock {
+		u, err := Lock(path + ".lock")
+		if err != nil {
+			return nil, fmt.Errorf("atomicfile: lock %s: %w", path, err)
+		}
+		unlock = u
+	}
+
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	// CreateTemp создаёт 0600, результат должен читаться как обычный файл
+	if err := f.Chmod(0o644); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		unlock()
+		return nil, err
+	}
+
+	af := &File{path: path, opts: opts, f: f, unlock: unlock}
+	var w io.Writer = f
+	if opts.Gzip {
+		af.zw = gzip.NewWriter(f)
+		w = af.zw
+	}
+	af.bw = bufio.NewWriterSize(w, 64*1024)
+	return af, nil
+}
+
+func (a *File) Write(p []byte) (int, error) {
+	return a.bw.Write(p)
+}
+
+// Commit дописывает буферы, fsync-ает файл, сдвигает бэкапы и публикует файл.
+func (a *File) Commit() error {
+	if a.done {
+		return fmt.Errorf("atomicfile: %s already closed", a.path)
+	}
+	a.done = true
+	defer a.unlock()
+
+	tmp := a.f.Name()
+	fail := func(err error) error {
+		_ = a.f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := a.bw.Flush(); err != nil {
+		return fail(err)
+	}
+	if a.zw != nil {
+		if err := a.zw.Close(); err != nil {
+			return fail(err)
+		}
+	}
+	if err := a.f.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := a.f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if a.opts.Backups > 0 {
+		if err := rotate(a.path, a.opts.Backups); err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+	}
+	if err := os.Rename(tmp, a.path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return syncDir(filepath.Dir(a.path))
+}
+
+// Abort удаляет временный файл; целевой файл не меняется.
+func (a *File) Abort() {
+	if a.done {
+		return
+	}
+	a.done = true
+	_ = a.f.Close()
+	_ = os.Remove(a.f.Name())
+	a.unlock()
+}
+
+// Write — запись целиком через write с настройками по умолчанию.
+func Write(path string, write func(w io.Writer) error) error {
+	return WriteWith(path, Options{}, write)
+}
+
+func WriteWith(path string, opts Options, write func(w io.Writer) error) error {
+	f, err := Create(path, opts)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Abort()
+		return err
+	}
+	return f.Commit()
+}
+
+// rotate: path.(n-1) → path.n, ..., path → path.1; самый старый удаляется.
+// path остаётся на месте (в path.1 — жёсткая ссылка на него), так что до
+// rename нового файла читатель видит прежнюю версию, а не пустоту.
+func rotate(path string, keep int) error {
+	backup := func(i int) string { return path + "." + strconv.Itoa(i) }
+
+	if err := os.Remove(backup(keep)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := keep - 1; i >= 1; i-- {
+		if err := os.Rename(backup(i), backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	err := os.Link(path, backup(1))
+	switch {
+	case err == nil, os.IsNotExist(err):
+		return nil
+	case os.IsExist(err):
+		return err
+	}
+	// ФС без жёстких ссылок — копия
+	return copyFile(path, backup(1))
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	// не все ФС умеют fsync каталога (например, часть сетевых) — это не ошибка записи
+	if err := d.Sync(); err != nil && !isSyncUnsupported(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package atomicfile
+
+// На платформах без flock блокировка не поддерживается: запись остаётся
+// атомарной (уникальный tmp + rename), но параллельные писатели не
+// выстраиваются в очередь.
+func Lock(path string) (func(), error) {
+	return func() {}, nil
+}
+
+// fsync каталога вне unix (windows) не поддерживается.
+func isSyncUnsupported(err error) bool {
+	return true
+}
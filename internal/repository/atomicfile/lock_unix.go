@@ -0,0 +1,54 @@
+//go:build unix
+
+package atomicfile
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Lock берёт эксклюзивный flock, ожидая, пока его отпустит другой процесс.
+// Владелец удаляет файл блокировки перед тем, как отпустить её; поэтому
+// после ожидания проверяется, что под path всё ещё наш файл, иначе
+// блокировка берётся заново на новом.
+func Lock(path string) (func(), error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+			if !errors.Is(err, syscall.EINTR) {
+				break
+			}
+		}
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+
+		held, herr := f.Stat()
+		cur, cerr := os.Stat(path)
+		if herr == nil && cerr == nil && os.SameFile(held, cur) {
+			return func() {
+				_ = os.Remove(path)
+				_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+				_ = f.Close()
+			}, nil
+		}
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+		if herr != nil {
+			return nil, herr
+		}
+		if cerr != nil && !os.IsNotExist(cerr) {
+			return nil, cerr
+		}
+	}
+}
+
+func isSyncUnsupported(err error) bool {
+	return errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTSUP)
+}
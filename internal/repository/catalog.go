@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"kuperparser/internal/domain/models"
+)
+
+// CategoryRef — категория, в которой найден товар; Path — названия от корня до листа.
+type CategoryRef struct {
+	ID   int      `json:"id"`
+	Slug string   `json:"slug,omitempty"`
+	Path []string `json:"path"`
+}
+
+// CatalogProduct — товар полного каталога; если он лежит в нескольких
+// категориях, все они перечислены в Categories. Потоковые форматы (ndjson)
+// сливают категории только в пределах раздела: товар из нескольких разделов
+// записан по разу на каждый.
+type CatalogProduct struct {
+	models.Product
+	Categories []CategoryRef `json:"categories"`
+}
+
+// CatalogResult — полный каталог магазина (режим -all).
+type CatalogResult struct {
+	SchemaVersion int              `json:"schema_version"`
+	FetchedAt     string           `json:"fetched_at"`
+	Store         *StoreMeta       `json:"store,omitempty"`
+	Categories    []CategoryNode   `json:"categories"`
+	Products      []CatalogProduct `json:"products"`
+	Count         int              `json:"count"`
+	// Departments — сколько разделов обошли; FailedDepartments — slug-и
+	// разделов, которые не удалось загрузить: товары раздела попадают в
+	// выход только после его последней страницы, так что от упавших
+	// разделов в каталоге нет ничего.
+	Departments       int      `json:"departments"`
+	FailedDepartments []string `json:"failed_departments,omitempty"`
+	Incomplete        bool     `json:"incomplete,omitempty"`
+}
+
+func (r CatalogResult) MarshalJSON() ([]byte, error) {
+	type plain CatalogResult
+	if r.SchemaVersion == 0 {
+		r.SchemaVersion = SchemaVersion
+	}
+	if r.Products == nil {
+		r.Products = []CatalogProduct{}
+	}
+	if r.Categories == nil {
+		r.Categories = []CategoryNode{}
+	}
+	return json.Marshal(plain(r))
+}
+
+// CatalogStream — потоковая запись полного каталога: товары приходят
+// пачками по разделам, метаданные и дерево категорий — в Close.
+type CatalogStream interface {
+	WriteProducts(ctx context.Context, products []CatalogProduct) error
+	// Close завершает запись; Products в summary игнорируется.
+	Close(summary CatalogResult) error
+	// Abort отменяет запись, частичный результат не публикуется.
+	Abort()
+}
+
+// CatalogSink — sink, умеющий сохранять полный каталог. Необязателен:
+// форматы без него при -all пропускаются (ErrUnsupported).
+// Tee и другие обёртки сообщают о поддержке через SupportsCatalog.
+type CatalogSink interface {
+	OpenCatalog(ctx context.Context) (CatalogStream, error)
+}
+
+// SupportsCatalog — сохранит ли sink полный каталог. Проверяется до обхода,
+// чтобы не выгружать магазин ради ErrUnsupported в конце.
+func SupportsCatalog(s Sink) bool {
+	if c, ok := s.(interface{ SupportsCatalog() bool }); ok {
+		return c.SupportsCatalog()
+	}
+	_, ok := s.(CatalogSink)
+	return ok
+}
+
+// OpenCatalog начинает запись каталога, если sink это умеет, иначе ErrUnsupported.
+func OpenCatalog(ctx context.Context, s Sink) (CatalogStream, error) {
+	cs, ok := s.(CatalogSink)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return cs.OpenCatalog(ctx)
+}
+
+// BufferedCatalog — CatalogStream для форматов, которые пишутся только целиком:
+// копит товары в памяти, сливая товар из нескольких разделов в одну запись
+// со всеми его категориями, и на Close вызывает save.
+func BufferedCatalog(ctx context.Context, save func(ctx context.Context, res CatalogResult) error) CatalogStream {
+	return &bufferedCatalog{ctx: ctx, save: save, index: make(map[string]int)}
+}
+
+type bufferedCatalog struct {
+	ctx      context.Context
+	save     func(ctx context.Context, res CatalogResult) error
+	products []CatalogProduct
+	index    map[string]int // Product.Key → позиция в products
+}
+
+func (b *bufferedCatalog) WriteProducts(ctx context.Context, products []CatalogProduct) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, p := range products {
+		k := p.Key()
+		if at, ok := b.index[k]; ok {
+			for _, ref := range p.Categories {
+				b.products[at].Categories = AppendRef(b.products[at].Categories, ref)
+			}
+			continue
+		}
+		b.index[k] = len(b.products)
+		b.products = append(b.products, p)
+	}
+	return nil
+}
+
+func (b *bufferedCatalog) Close(summary CatalogResult) error {
+	summary.Products = b.products
+	summary.Count = len(b.products)
+	return b.save(b.ctx, summary)
+}
+
+func (b *bufferedCatalog) Abort() {
+	b.products, b.index = nil, nil
+}
+
+// AppendRef добавляет категорию, если её ещё нет в списке.
+func AppendRef(refs []CategoryRef, ref CategoryRef) []CategoryRef {
+	for _, r := range refs {
+		if r.ID == ref.ID && r.Slug == ref.Slug {
+			return refs
+		}
+	}
+	return append(refs, ref)
+}
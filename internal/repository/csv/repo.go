@@ -0,0 +1,253 @@
+package csvfile
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"kuperparser/internal/domain/models"
+	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/atomicfile"
+)
+
+const bom = "\xEF\xBB\xBF"
+
+// Колонки товаров. Метаданные магазина/категории повторяются в каждой строке,
+// чтобы файл можно было сразу фильтровать и сводить в таблицах.
+var ProductColumns = []string{
+	"fetched_at",
+	"store_id",
+	"store_name",
+	"store_address",
+	"retailer_name",
+	"category_id",
+	"category_slug",
+	"category_path", // только в полном каталоге: пути всех категорий товара
+	"name",
+	"price",
+	"url",
+}
+
+var DefaultColumns = []string{
+	"store_id",
+	"store_name",
+	"category_id",
+	"category_slug",
+	"name",
+	"price",
+	"url",
+}
+
+var StoreColumns = []string{"id", "name", "address", "retailer_name"}
+
+type Options struct {
+	Delimiter rune     // ',' ';' '\t'; 0 — ','
+	BOM       bool     // UTF-8 BOM, чтобы Excel правильно открыл кириллицу
+	Columns   []string // колонки товаров и их порядок; пусто — DefaultColumns
+}
+
+type Repo struct {
+	Path string
+	Log  *slog.Logger
+	Opts Options
+}
+
+func New(path string, opts Options, log *slog.Logger) (*Repo, error) {
+	if log == nil {
+		log = slog.Default()
+	}
+	if opts.Delimiter == 0 {
+		opts.Delimiter = ','
+	}
+	if len(opts.Columns) == 0 {
+		opts.Columns = DefaultColumns
+	}
+	for _, c := range opts.Columns {
+		if !knownColumn(c) {
+			return nil, fmt.Errorf("csv repo: unknown column %q", c)
+		}
+	}
+	return &Repo{Path: path, Log: log, Opts: opts}, nil
+}
+
+func knownColumn(c string) bool {
+	return contains(ProductColumns, c)
+}
+
+func (r *Repo) SaveCategory(ctx context.Context, res repository.CategoryResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := r.write(r.Opts.Columns, res.Incomplete, func(w *csv.Writer) error {
+		for _, p := range res.Products {
+			if err := w.Write(productRow(r.Opts.Columns, res, p)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.Log.Info("csv saved", "path", r.Path, "count", res.Count)
+	return nil
+}
+
+func (r *Repo) OpenCategory(ctx context.Context) (repository.CategoryStream, error) {
+	return repository.BufferedStream(ctx, r.SaveCategory), nil
+}
+
+func (r *Repo) OpenCatalog(ctx context.Context) (repository.CatalogStream, error) {
+	return repository.BufferedCatalog(ctx, r.SaveCatalog), nil
+}
+
+func (r *Repo) SaveStores(ctx context.Context, res repository.StoresResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := r.write(StoreColumns, res.Incomplete, func(w *csv.Writer) error {
+		for _, s := range res.Stores {
+			row := []string{strconv.Itoa(s.ID), s.Name, s.Address, s.RetailerName}
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.Log.Info("stores csv saved", "path", r.Path, "count", res.Count)
+	return nil
+}
+
+// SaveCatalog: строка на товар; category_id/category_slug — первая категория
+// товара, category_path — все его категории ("A / B | C / D"). Колонка
+// category_path добавляется в конец, если её нет в списке.
+func (r *Repo) SaveCatalog(ctx context.Context, res repository.CatalogResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	cols := r.Opts.Columns
+	if !contains(cols, "category_path") {
+		cols = append(append([]string{}, cols...), "category_path")
+	}
+
+	err := r.write(cols, res.Incomplete, func(w *csv.Writer) error {
+		for _, p := range res.Products {
+			row := repository.CategoryResult{FetchedAt: res.FetchedAt, Store: res.Store}
+			paths := make([]string, len(p.Categories))
+			for i, c := range p.Categories {
+				paths[i] = strings.Join(c.Path, " / ")
+			}
+			if len(p.Categories) > 0 {
+				row.Category = &repository.CategoryMeta{ID: p.Categories[0].ID, Slug: p.Categories[0].Slug}
+			}
+			rec := productRow(cols, row, p.Product)
+			for i, c := range cols {
+				if c == "category_path" {
+					rec[i] = strings.Join(paths, " | ")
+				}
+			}
+			if err := w.Write(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.Log.Info("catalog csv saved", "path", r.Path, "count", res.Count)
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// incompleteMarker — последняя строка неполной выгрузки (остановлена
+// сигналом или дедлайном), как комментарий в шапке SQL-дампа.
+const incompleteMarker = "# incomplete: crawl was interrupted - partial data"
+
+func (r *Repo) write(header []string, incomplete bool, rows func(w *csv.Writer) error) error {
+	return atomicfile.Write(r.Path, func(out io.Writer) error {
+		if r.Opts.BOM {
+			if _, err := io.WriteString(out, bom); err != nil {
+				return err
+			}
+		}
+
+		w := csv.NewWriter(out)
+		w.Comma = r.Opts.Delimiter
+		if err := w.Write(header); err != nil {
+			return err
+		}
+		if err := rows(w); err != nil {
+			return err
+		}
+		if incomplete {
+			if err := w.Write([]string{incompleteMarker}); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	})
+}
+
+func productRow(cols []string, res repository.CategoryResult, p models.Product) []string {
+	row := make([]string, len(cols))
+	for i, c := range cols {
+		switch c {
+		case "fetched_at":
+			row[i] = res.FetchedAt
+		case "store_id":
+			if res.Store != nil {
+				row[i] = strconv.Itoa(res.Store.ID)
+			}
+		case "store_name":
+			if res.Store != nil {
+				row[i] = res.Store.Name
+			}
+		case "store_address":
+			if res.Store != nil {
+				row[i] = res.Store.Address
+			}
+		case "retailer_name":
+			if res.Store != nil {
+				row[i] = res.Store.RetailerName
+			}
+		case "category_id":
+			if res.Category != nil {
+				row[i] = strconv.Itoa(res.Category.ID)
+			}
+		case "category_slug":
+			if res.Category != nil {
+				row[i] = res.Category.Slug
+			}
+		case "name":
+			row[i] = p.Name
+		case "price":
+			row[i] = p.Price
+		case "url":
+			row[i] = p.URL
+		}
+	}
+	return row
+}
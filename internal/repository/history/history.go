@@ -0,0 +1,440 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"kuperparser/internal/domain/models"
+	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/atomicfile"
+)
+
+// Локальная история цен, только дозапись:
+//
+//	<dir>/segments/2006-01-02.ndjson — товары запусков за день (UTC),
+//	                                   строка на товар с id запуска;
+//	<dir>/index.ndjson               — строка на завершённый запуск: магазин,
+//	                                   категория, fetched_at, сегмент.
+//
+// Товары пишутся в сегмент по мере загрузки, запись в индекс — последней,
+// после fsync сегмента. Запуск, оборвавшийся до индекса, в запросах не виден.
+// Писатели (CLI, API, несколько процессов) сериализуются через flock <dir>/.lock.
+
+const (
+	indexFile   = "index.ndjson"
+	segmentsDir = "segments"
+	dayLayout   = "2006-01-02"
+)
+
+type Store struct {
+	dir string
+	log *slog.Logger
+
+	mu sync.Mutex // flock не различает горутины одного процесса
+}
+
+func New(dir string, log *slog.Logger) *Store {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Store{dir: dir, log: log}
+}
+
+func (s *Store) Dir() string { return s.dir }
+
+// Run — запись индекса о завершённом запуске.
+type Run struct {
+	ID        string                   `json:"run"`
+	FetchedAt string                   `json:"fetched_at"`
+	Segment   string                   `json:"segment"`
+	Store     repository.StoreMeta     `json:"store"`
+	Category  *repository.CategoryMeta `json:"category,omitempty"`
+	Count     int                      `json:"count"`
+}
+
+// строка сегмента
+type record struct {
+	Run string `json:"run"`
+	models.Product
+}
+
+// PricePoint — цена товара в одном запуске.
+type PricePoint struct {
+	FetchedAt  string `json:"fetched_at"`
+	StoreID    int    `json:"store_id"`
+	CategoryID int    `json:"category_id,omitempty"`
+	Name       string `json:"name"`
+	Price      string `json:"price"`
+}
+
+// Append сохраняет результат целиком. storeID — на случай, если метаданные
+// магазина получить не удалось (res.Store == nil).
+func (s *Store) Append(ctx context.Context, storeID int, res repository.CategoryResult) error {
+	w, err := s.begin(storeID)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteProducts(ctx, res.Products); err != nil {
+		w.Abort()
+		return err
+	}
+	if res.Count == 0 {
+		res.Count = len(res.Products)
+	}
+	return w.Close(res)
+}
+
+// Sink — адаптер под repository.Sink для tee CLI. Магазины в историю не пишутся.
+func (s *Store) Sink(storeID int) repository.Sink {
+	return &sink{store: s, storeID: storeID}
+}
+
+type sink struct {
+	store   *Store
+	storeID int
+}
+
+func (k *sink) SaveCategory(ctx context.Context, res repository.CategoryResult) error {
+	return k.store.Append(ctx, k.storeID, res)
+}
+
+func (k *sink) OpenCategory(ctx context.Context) (repository.CategoryStream, error) {
+	return k.store.begin(k.storeID)
+}
+
+func (k *sink) SaveStores(ctx context.Context, res repository.StoresResult) error {
+	return repository.ErrUnsupported
+}
+
+type writer struct {
+	store   *Store
+	storeID int
+	run     string
+	segment string
+	count   int
+	done    bool
+}
+
+func (s *Store) begin(storeID int) (*writer, error) {
+	if s.dir == "" {
+		return nil, fmt.Errorf("history: empty dir")
+	}
+	if err := os.MkdirAll(filepath.Join(s.dir, segmentsDir), 0o755); err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+
+	now := time.Now().UTC()
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+
+	return &writer{
+		store:   s,
+		storeID: storeID,
+		run:     now.Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix),
+		segment: now.Format(dayLayout) + ".ndjson",
+	}, nil
+}
+
+func (w *writer) WriteProducts(ctx context.Context, products []models.Product) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(products) == 0 {
+		return nil
+	}
+
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for _, p := range products {
+		if err := enc.Encode(record{Run: w.run, Product: p}); err != nil {
+			return err
+		}
+	}
+	if err := w.store.append(filepath.Join(segmentsDir, w.segment), b.Bytes(), ""); err != nil {
+		return fmt.Errorf("history: write segment: %w", err)
+	}
+	w.count += len(products)
+	return nil
+}
+
+func (w *writer) Close(summary repository.CategoryResult) error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	// неполный снимок в истории выглядел бы как исчезнувшие товары
+	if summary.Incomplete {
+		w.store.log.Info("incomplete run is not recorded in history", "run", w.run, "count", w.count)
+		return nil
+	}
+
+	run := Run{
+		ID:        w.run,
+		FetchedAt: summary.FetchedAt,
+		Segment:   w.segment,
+		Store:     repository.StoreMeta{ID: w.storeID},
+		Category:  summary.Category,
+		Count:     summary.Count,
+	}
+	if summary.Store != nil {
+		run.Store = *summary.Store
+	}
+	if run.FetchedAt == "" {
+		run.FetchedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	if run.Count == 0 {
+		run.Count = w.count
+	}
+	if run.Store.ID <= 0 {
+		return fmt.Errorf("history: store id is unknown")
+	}
+
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	// сегмент fsync-ается внутри append перед записью индекса
+	if err := w.store.append(indexFile, append(line, '\n'), filepath.Join(segmentsDir, w.segment)); err != nil {
+		return fmt.Errorf("history: write index: %w", err)
+	}
+	w.store.log.Debug("history run saved", "run", run.ID, "store_id", run.Store.ID, "count", run.Count)
+	return nil
+}
+
+// Abort: строки уже дописаны в сегмент, но без записи в индексе их никто не увидит.
+func (w *writer) Abort() {
+	w.done = true
+}
+
+// append дописывает data в файл под блокировкой. Если задан syncFirst
+// (сегмент запуска при записи индекса), он fsync-ается до записи,
+// а сам файл — после.
+func (s *Store) append(name string, data []byte, syncFirst string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := atomicfile.Lock(filepath.Join(s.dir, ".lock"))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if syncFirst != "" {
+		if err := syncFile(filepath.Join(s.dir, syncFirst)); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.dir, name), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	// после сбоя файл может оканчиваться оборванной строкой — не склеиваем с ней
+	if st, err := f.Stat(); err == nil && st.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, st.Size()-1); err == nil && last[0] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if syncFirst != "" {
+		if err := f.Sync(); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+func syncFile(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+// Runs — завершённые запуски по индексу, в порядке записи.
+func (s *Store) Runs() ([]Run, error) {
+	f, err := os.Open(filepath.Join(s.dir, indexFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []Run
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var r Run
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			// недописанная последняя строка после сбоя — пропускаем
+			s.log.Warn("history: bad index line, skipped", "line", n, "err", err)
+			continue
+		}
+		out = append(out, r)
+	}
+	return out, sc.Err()
+}
+
+// Snapshot — состояние категории магазина на момент at: последний запуск
+// с fetched_at <= at.
+func (s *Store) Snapshot(storeID, categoryID int, at time.Time) (repository.CategoryResult, error) {
+	var res repository.CategoryResult
+
+	runs, err := s.Runs()
+	if err != nil {
+		return res, err
+	}
+
+	var (
+		best   *Run
+		bestAt time.Time
+	)
+	for i := range runs {
+		r := &runs[i]
+		if r.Store.ID != storeID || r.Category == nil || r.Category.ID != categoryID {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, r.FetchedAt)
+		if err != nil || t.After(at) {
+			continue
+		}
+		if best == nil || !t.Before(bestAt) {
+			best, bestAt = r, t
+		}
+	}
+	if best == nil {
+		return res, fmt.Errorf("history: no snapshot of store %d category %d at %s", storeID, categoryID, at.Format(time.RFC3339))
+	}
+
+	products := []models.Product{}
+	err = s.scan(best.Segment, func(rec record) {
+		if rec.Run == best.ID {
+			products = append(products, rec.Product)
+		}
+	})
+	if err != nil {
+		return res, err
+	}
+
+	store := best.Store
+	return repository.CategoryResult{
+		FetchedAt: best.FetchedAt,
+		Store:     &store,
+		Category:  best.Category,
+		Products:  products,
+		Count:     len(products),
+	}, nil
+}
+
+// Timeline — цены товара (по url) в запусках из [from, to]; storeID 0 — все
+// магазины. Нулевые from/to — без ограничения. Сортировка по времени.
+func (s *Store) Timeline(storeID int, url string, from, to time.Time) ([]PricePoint, error) {
+	runs, err := s.Runs()
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]Run)
+	bySegment := make(map[string]bool)
+	for _, r := range runs {
+		if storeID > 0 && r.Store.ID != storeID {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, r.FetchedAt)
+		if err != nil || (!from.IsZero() && t.Before(from)) || (!to.IsZero() && t.After(to)) {
+			continue
+		}
+		byID[r.ID] = r
+		bySegment[r.Segment] = true
+	}
+
+	segments := make([]string, 0, len(bySegment))
+	for seg := range bySegment {
+		segments = append(segments, seg)
+	}
+	sort.Strings(segments)
+
+	out := []PricePoint{}
+	for _, seg := range segments {
+		err := s.scan(seg, func(rec record) {
+			r, ok := byID[rec.Run]
+			if !ok || rec.URL != url {
+				return
+			}
+			p := PricePoint{
+				FetchedAt: r.FetchedAt,
+				StoreID:   r.Store.ID,
+				Name:      rec.Name,
+				Price:     rec.Price,
+			}
+			if r.Category != nil {
+				p.CategoryID = r.Category.ID
+			}
+			out = append(out, p)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// RFC3339 в UTC сортируется как строка
+	sort.SliceStable(out, func(i, j int) bool { return out[i].FetchedAt < out[j].FetchedAt })
+	return out, nil
+}
+
+func (s *Store) scan(segment string, fn func(rec record)) error {
+	f, err := os.Open(filepath.Join(s.dir, segmentsDir, filepath.Base(segment)))
+	if err != nil {
+		// запуск без товаров сегмент не создаёт
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var rec record
+			if json.Unmarshal(line, &rec) == nil {
+				fn(rec)
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
@@ -4,16 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
-	"os"
-	"path/filepath"
 
 	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/atomicfile"
 )
 
 type Repo struct {
 	Path string
 	Log  *slog.Logger
+	Opts atomicfile.Options
 }
 
 func New(path string, log *slog.Logger) *Repo {
@@ -23,7 +24,7 @@ func New(path string, log *slog.Logger) *Repo {
 	return &Repo{Path: path, Log: log}
 }
 
-func (r *Repo) Save(ctx context.Context, res repository.CategoryResult) error {
+func (r *Repo) SaveCategory(ctx context.Context, res repository.CategoryResult) error {
 	if err := r.saveAny(ctx, res); err != nil {
 		return err
 	}
@@ -31,6 +32,14 @@ func (r *Repo) Save(ctx context.Context, res repository.CategoryResult) error {
 	return nil
 }
 
+func (r *Repo) OpenCategory(ctx context.Context) (repository.CategoryStream, error) {
+	return repository.BufferedStream(ctx, r.SaveCategory), nil
+}
+
+func (r *Repo) OpenCatalog(ctx context.Context) (repository.CatalogStream, error) {
+	return repository.BufferedCatalog(ctx, r.SaveCatalog), nil
+}
+
 func (r *Repo) SaveStores(ctx context.Context, res repository.StoresResult) error {
 	if err := r.saveAny(ctx, res); err != nil {
 		return err
@@ -39,6 +48,14 @@ func (r *Repo) SaveStores(ctx context.Context, res repository.StoresResult) erro
 	return nil
 }
 
+func (r *Repo) SaveCatalog(ctx context.Context, res repository.CatalogResult) error {
+	if err := r.saveAny(ctx, res); err != nil {
+		return err
+	}
+	r.Log.Info("catalog json saved", "path", r.Path, "count", res.Count)
+	return nil
+}
+
 func (r *Repo) saveAny(ctx context.Context, v any) error {
 	if err := ctx.Err(); err != nil {
 		return err
@@ -53,21 +70,9 @@ func (r *Repo) saveAny(ctx context.Context, v any) error {
 	}
 	b = append(b, '\n')
 
-	dir := filepath.Dir(r.Path)
-	if dir != "." && dir != "" {
-		if err := os.MkdirAll(dir, 0o755); err != nil {
-			return err
-		}
-	}
-
-	tmp := r.Path + ".tmp"
-	if err := os.WriteFile(tmp, b, 0o644); err != nil {
-		return err
-	}
-	if err := os.Rename(tmp, r.Path); err != nil {
-		_ = os.Remove(tmp)
+	// уникальный tmp + fsync + rename; .gz — сжатие, Lock/Backups — из Opts
+	return atomicfile.WriteWith(r.Path, r.Opts, func(w io.Writer) error {
+		_, err := w.Write(b)
 		return err
-	}
-
-	return nil
+	})
 }
@@ -0,0 +1,185 @@
+package ndjson
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"kuperparser/internal/domain/models"
+	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/atomicfile"
+)
+
+// Формат: по строке JSON на товар ({"type":"product",...}), последней строкой —
+// сводка ({"type":"summary",...}) с метаданными и количеством. Файл пишется во
+// временный файл и появляется под своим именем только после Close.
+
+const (
+	TypeProduct = "product"
+	TypeSummary = "summary"
+)
+
+// ProductRecord — строка с товаром; Categories — только в полном каталоге.
+type ProductRecord struct {
+	Type string `json:"type"`
+	models.Product
+	Categories []repository.CategoryRef `json:"categories,omitempty"`
+}
+
+// SummaryRecord — последняя строка файла.
+type SummaryRecord struct {
+	Type          string                   `json:"type"`
+	SchemaVersion int                      `json:"schema_version"`
+	FetchedAt     string                   `json:"fetched_at"`
+	Store         *repository.StoreMeta    `json:"store,omitempty"`
+	Category      *repository.CategoryMeta `json:"category,omitempty"`
+	Count         int                      `json:"count"`
+	Incomplete    bool                     `json:"incomplete,omitempty"`
+}
+
+// Stream пишет товары по мере поступления, не держа их в памяти.
+type Stream struct {
+	path string
+	log  *slog.Logger
+
+	f     *atomicfile.File
+	enc   *json.Encoder
+	count int
+}
+
+func Open(path string, log *slog.Logger) (*Stream, error) {
+	return OpenWith(path, atomicfile.Options{}, log)
+}
+
+// OpenWith — Open с настройками записи файла (блокировка, бэкапы; .gz — сжатие).
+func OpenWith(path string, opts atomicfile.Options, log *slog.Logger) (*Stream, error) {
+	if log == nil {
+		log = slog.Default()
+	}
+	if path == "" {
+		return nil, fmt.Errorf("ndjson: empty path")
+	}
+
+	f, err := atomicfile.Create(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Stream{path: path, log: log, f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (s *Stream) WriteProducts(ctx context.Context, products []models.Product) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, p := range products {
+		if err := s.enc.Encode(ProductRecord{Type: TypeProduct, Product: p}); err != nil {
+			return err
+		}
+		s.count++
+	}
+	return nil
+}
+
+// Count — сколько товаров уже записано.
+func (s *Stream) Count() int {
+	return s.count
+}
+
+// Close дописывает сводку и публикует файл. Count в сводке берётся из
+// фактически записанных строк, Products игнорируется.
+func (s *Stream) Close(summary repository.CategoryResult) error {
+	err := s.enc.Encode(SummaryRecord{
+		Type:          TypeSummary,
+		SchemaVersion: repository.SchemaVersion,
+		FetchedAt:     summary.FetchedAt,
+		Store:         summary.Store,
+		Category:      summary.Category,
+		Count:         s.count,
+		Incomplete:    summary.Incomplete,
+	})
+	if err != nil {
+		s.f.Abort()
+		return err
+	}
+	if err := s.f.Commit(); err != nil {
+		return err
+	}
+
+	s.log.Info("ndjson saved", "path", s.path, "count", s.count)
+	return nil
+}
+
+// Abort удаляет недописанный файл.
+func (s *Stream) Abort() {
+	s.f.Abort()
+}
+
+// Repo — repository.Sink поверх Stream.
+type Repo struct {
+	Path string
+	Log  *slog.Logger
+	Opts atomicfile.Options
+}
+
+func New(path string, log *slog.Logger) *Repo {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Repo{Path: path, Log: log}
+}
+
+func (r *Repo) OpenCategory(ctx context.Context) (repository.CategoryStream, error) {
+	s, err := OpenWith(r.Path, r.Opts, r.Log)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *Repo) SaveStores(ctx context.Context, res repository.StoresResult) error {
+	return fmt.Errorf("ndjson repo: stores: %w", repository.ErrUnsupported)
+}
+
+func (r *Repo) SaveCategory(ctx context.Context, res repository.CategoryResult) error {
+	s, err := OpenWith(r.Path, r.Opts, r.Log)
+	if err != nil {
+		return err
+	}
+	if err := s.WriteProducts(ctx, res.Products); err != nil {
+		s.Abort()
+		return err
+	}
+	return s.Close(res)
+}
+
+// OpenCatalog пишет полный каталог по мере обхода: строка на товар с его
+// категориями, затем сводка. Товар из нескольких разделов — строка на раздел.
+func (r *Repo) OpenCatalog(ctx context.Context) (repository.CatalogStream, error) {
+	s, err := OpenWith(r.Path, r.Opts, r.Log)
+	if err != nil {
+		return nil, err
+	}
+	return catalogStream{s}, nil
+}
+
+type catalogStream struct {
+	*Stream
+}
+
+func (s catalogStream) WriteProducts(ctx context.Context, products []repository.CatalogProduct) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, p := range products {
+		if err := s.enc.Encode(ProductRecord{Type: TypeProduct, Product: p.Product, Categories: p.Categories}); err != nil {
+			return err
+		}
+		s.count++
+	}
+	return nil
+}
+
+func (s catalogStream) Close(summary repository.CatalogResult) error {
+	return s.Stream.Close(repository.CategoryResult{FetchedAt: summary.FetchedAt, Store: summary.Store, Incomplete: summary.Incomplete})
+}
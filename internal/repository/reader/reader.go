@@ -0,0 +1,254 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"kuperparser/internal/domain/models"
+	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/ndjson"
+)
+
+// Чтение ранее сохранённых результатов (json и ndjson, в том числе .gz)
+// с подъёмом старых версий схемы до текущей в памяти.
+
+// LoadCategory читает результат категории из файла.
+func LoadCategory(path string) (repository.CategoryResult, error) {
+	var res repository.CategoryResult
+	err := withFile(path, func(r io.Reader) error {
+		var err error
+		res, err = DecodeCategory(r)
+		return err
+	})
+	if err != nil {
+		return res, fmt.Errorf("load %s: %w", path, err)
+	}
+	return res, nil
+}
+
+// LoadStores читает результат сканирования магазинов из файла.
+func LoadStores(path string) (repository.StoresResult, error) {
+	var res repository.StoresResult
+	err := withFile(path, func(r io.Reader) error {
+		var err error
+		res, err = DecodeStores(r)
+		return err
+	})
+	if err != nil {
+		return res, fmt.Errorf("load %s: %w", path, err)
+	}
+	return res, nil
+}
+
+// Document — результат любого вида: заполнено ровно одно поле.
+type Document struct {
+	Category *repository.CategoryResult
+	Stores   *repository.StoresResult
+}
+
+// Load читает файл, определяя вид результата по содержимому
+// (NDJSON или json с products — категория, json со stores — магазины).
+func Load(path string) (Document, error) {
+	var doc Document
+	err := withFile(path, func(r io.Reader) error {
+		var err error
+		doc, err = Decode(r)
+		return err
+	})
+	if err != nil {
+		return doc, fmt.Errorf("load %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+func Decode(r io.Reader) (Document, error) {
+	dec := json.NewDecoder(r)
+	var first map[string]json.RawMessage
+	if err := dec.Decode(&first); err != nil {
+		return Document{}, err
+	}
+
+	if _, ok := first["stores"]; ok {
+		res, err := stores(first)
+		return Document{Stores: &res}, err
+	}
+	res, err := category(first, dec)
+	return Document{Category: &res}, err
+}
+
+func withFile(path string, fn func(r io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReader(f)
+	if strings.HasSuffix(strings.ToLower(path), ".gz") {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	return fn(r)
+}
+
+// DecodeCategory читает json-документ или NDJSON-поток (формат определяется
+// по первой записи) и поднимает версию схемы до текущей.
+func DecodeCategory(r io.Reader) (repository.CategoryResult, error) {
+	dec := json.NewDecoder(r)
+	var first map[string]json.RawMessage
+	if err := dec.Decode(&first); err != nil {
+		return repository.CategoryResult{}, err
+	}
+	return category(first, dec)
+}
+
+func category(first map[string]json.RawMessage, dec *json.Decoder) (repository.CategoryResult, error) {
+	var res repository.CategoryResult
+	if _, ok := first["type"]; ok {
+		return decodeNDJSON(first, dec)
+	}
+
+	if err := upgrade(first, "category"); err != nil {
+		return res, err
+	}
+	return res, remarshal(first, &res)
+}
+
+func DecodeStores(r io.Reader) (repository.StoresResult, error) {
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return repository.StoresResult{}, err
+	}
+	return stores(doc)
+}
+
+func stores(doc map[string]json.RawMessage) (repository.StoresResult, error) {
+	var res repository.StoresResult
+	if err := upgrade(doc, "stores"); err != nil {
+		return res, err
+	}
+	return res, remarshal(doc, &res)
+}
+
+func decodeNDJSON(first map[string]json.RawMessage, dec *json.Decoder) (repository.CategoryResult, error) {
+	res := repository.CategoryResult{Products: []models.Product{}}
+	var summary *ndjson.SummaryRecord
+
+	line := first
+	for {
+		var kind string
+		_ = json.Unmarshal(line["type"], &kind)
+
+		switch kind {
+		case ndjson.TypeProduct:
+			var rec ndjson.ProductRecord
+			if err := remarshal(line, &rec); err != nil {
+				return res, err
+			}
+			res.Products = append(res.Products, rec.Product)
+		case ndjson.TypeSummary:
+			summary = &ndjson.SummaryRecord{}
+			if err := remarshal(line, summary); err != nil {
+				return res, err
+			}
+		default:
+			return res, fmt.Errorf("ndjson: unknown record type %q", kind)
+		}
+
+		line = nil
+		if err := dec.Decode(&line); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return res, err
+		}
+	}
+
+	// без сводки файл не дописан (запись обрывается до Close)
+	if summary == nil {
+		return res, fmt.Errorf("ndjson: summary record not found, output is incomplete")
+	}
+	if summary.SchemaVersion > repository.SchemaVersion {
+		return res, newerErr(summary.SchemaVersion)
+	}
+	res.SchemaVersion = repository.SchemaVersion
+	res.FetchedAt = summary.FetchedAt
+	res.Store = summary.Store
+	res.Category = summary.Category
+	res.Count = summary.Count
+	res.Incomplete = summary.Incomplete
+	return res, nil
+}
+
+// upgraders[v] поднимает документ с версии v до v+1.
+var upgraders = map[int]func(doc map[string]json.RawMessage, kind string) error{
+	0: upgradeV0,
+}
+
+func upgrade(doc map[string]json.RawMessage, kind string) error {
+	v := 0
+	if raw, ok := doc["schema_version"]; ok {
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return fmt.Errorf("schema_version: %w", err)
+		}
+	}
+	if v > repository.SchemaVersion {
+		return newerErr(v)
+	}
+	for ; v < repository.SchemaVersion; v++ {
+		step, ok := upgraders[v]
+		if !ok {
+			return fmt.Errorf("no upgrade from schema_version %d", v)
+		}
+		if err := step(doc, kind); err != nil {
+			return fmt.Errorf("upgrade from schema_version %d: %w", v, err)
+		}
+	}
+	doc["schema_version"] = json.RawMessage(fmt.Sprint(repository.SchemaVersion))
+	return nil
+}
+
+// upgradeV0: products/stores: null → [], нет count — считаем по списку.
+func upgradeV0(doc map[string]json.RawMessage, kind string) error {
+	list := "products"
+	if kind == "stores" {
+		list = "stores"
+	}
+
+	raw := bytes.TrimSpace(doc[list])
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		doc[list] = json.RawMessage("[]")
+		raw = doc[list]
+	}
+	if _, ok := doc["count"]; !ok {
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return fmt.Errorf("%s: %w", list, err)
+		}
+		doc["count"] = json.RawMessage(fmt.Sprint(len(items)))
+	}
+	return nil
+}
+
+func newerErr(v int) error {
+	return fmt.Errorf("schema_version %d is newer than supported %d, update kuperparser", v, repository.SchemaVersion)
+}
+
+func remarshal(doc map[string]json.RawMessage, v any) error {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -1,9 +1,18 @@
 package repository
 
 import (
+	"encoding/json"
+
 	"kuperparser/internal/domain/models"
 )
 
+// SchemaVersion — версия формата результатов. Поднимается при любом
+// несовместимом изменении полей; reader умеет поднимать старые версии.
+//
+//	0 — до появления schema_version;
+//	1 — schema_version, products всегда массив, count обязателен.
+const SchemaVersion = 1
+
 type StoreMeta struct {
 	ID           int    `json:"id"`
 	Name         string `json:"name,omitempty"`
@@ -17,15 +26,52 @@ type CategoryMeta struct {
 }
 
 type CategoryResult struct {
-	FetchedAt string           `json:"fetched_at"`
-	Store     *StoreMeta       `json:"store,omitempty"`
-	Category  *CategoryMeta    `json:"category,omitempty"`
-	Products  []models.Product `json:"products"`
-	Count     int              `json:"count"`
+	SchemaVersion int              `json:"schema_version"`
+	FetchedAt     string           `json:"fetched_at"`
+	Store         *StoreMeta       `json:"store,omitempty"`
+	Category      *CategoryMeta    `json:"category,omitempty"`
+	Products      []models.Product `json:"products"`
+	Count         int              `json:"count"`
+	// Incomplete — выгрузку прервали (сигнал, дедлайн), сохранено то, что успели
+	Incomplete bool `json:"incomplete,omitempty"`
 }
 
 type StoresResult struct {
-	FetchedAt string      `json:"fetched_at"`
-	Stores    []StoreMeta `json:"stores"`
-	Count     int         `json:"count"`
+	SchemaVersion int         `json:"schema_version"`
+	FetchedAt     string      `json:"fetched_at"`
+	Stores        []StoreMeta `json:"stores"`
+	Count         int         `json:"count"`
+	Incomplete    bool        `json:"incomplete,omitempty"`
+}
+
+// MarshalJSON проставляет текущую версию схемы, если она не задана,
+// чтобы её не приходилось помнить в каждом месте, где собирается результат.
+func (r CategoryResult) MarshalJSON() ([]byte, error) {
+	type plain CategoryResult
+	if r.SchemaVersion == 0 {
+		r.SchemaVersion = SchemaVersion
+	}
+	if r.Products == nil {
+		r.Products = []models.Product{}
+	}
+	return json.Marshal(plain(r))
+}
+
+func (r StoresResult) MarshalJSON() ([]byte, error) {
+	type plain StoresResult
+	if r.SchemaVersion == 0 {
+		r.SchemaVersion = SchemaVersion
+	}
+	if r.Stores == nil {
+		r.Stores = []StoreMeta{}
+	}
+	return json.Marshal(plain(r))
+}
+
+// CategoryNode — узел дерева категорий магазина (плоским списком, связь через ParentID).
+type CategoryNode struct {
+	ID       int    `json:"id"`
+	ParentID int    `json:"parent_id,omitempty"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug,omitempty"`
 }
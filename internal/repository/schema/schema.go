@@ -0,0 +1,186 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/ndjson"
+)
+
+// JSON Schema (draft 2020-12) для выходных форматов строится из Go-структур
+// по json-тегам: omitempty — необязательное поле, указатель — тоже,
+// встроенные структуры раскрываются. Лишние поля разрешены, чтобы старые
+// потребители не ломались на новых необязательных полях.
+
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+type Schema struct {
+	Schema      string      `json:"$schema,omitempty"`
+	ID          string      `json:"$id,omitempty"`
+	Title       string      `json:"title,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Type        string      `json:"type,omitempty"`
+	Const       any         `json:"const,omitempty"`
+	Properties  *Properties `json:"properties,omitempty"`
+	Required    []string    `json:"required,omitempty"`
+	Items       *Schema     `json:"items,omitempty"`
+}
+
+// Properties сохраняет порядок полей как в структуре.
+type Properties struct {
+	names []string
+	items map[string]*Schema
+}
+
+func (p *Properties) set(name string, s *Schema) {
+	if p.items == nil {
+		p.items = make(map[string]*Schema)
+	}
+	if _, ok := p.items[name]; !ok {
+		p.names = append(p.names, name)
+	}
+	p.items[name] = s
+}
+
+func (p *Properties) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, name := range p.names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(name)
+		v, err := json.Marshal(p.items[name])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+type document struct {
+	name        string
+	description string
+	value       any
+	kind        string // значение поля type у строк NDJSON
+}
+
+// документы по имени; имя же идёт в $id
+var documents = []document{
+	{"category_result", "Результат выгрузки категории (json, webhook data, kind=category).", repository.CategoryResult{}, ""},
+	{"catalog_result", "Полный каталог магазина (-all: json, webhook data, kind=catalog).", repository.CatalogResult{}, ""},
+	{"stores_result", "Результат сканирования магазинов (json, webhook data, kind=stores).", repository.StoresResult{}, ""},
+	{"ndjson_product", "Строка NDJSON-выгрузки с товаром (type=product).", ndjson.ProductRecord{}, ndjson.TypeProduct},
+	{"ndjson_summary", "Последняя строка NDJSON-выгрузки (type=summary).", ndjson.SummaryRecord{}, ndjson.TypeSummary},
+}
+
+// Names — имена всех документов.
+func Names() []string {
+	out := make([]string, len(documents))
+	for i, d := range documents {
+		out[i] = d.name
+	}
+	return out
+}
+
+// Get возвращает схему документа по имени.
+func Get(name string) (*Schema, error) {
+	for _, d := range documents {
+		if d.name == name {
+			s := Generate(reflect.TypeOf(d.value))
+			s.Schema = Draft
+			s.ID = fmt.Sprintf("kuperparser/%s/v%d", d.name, repository.SchemaVersion)
+			s.Title = d.name
+			s.Description = d.description
+			if d.kind != "" {
+				s.Properties.items["type"].Const = d.kind
+			}
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown schema %q (expected %s)", name, strings.Join(Names(), "|"))
+}
+
+// All — все схемы, по имени.
+func All() map[string]*Schema {
+	out := make(map[string]*Schema, len(documents))
+	for _, d := range documents {
+		out[d.name], _ = Get(d.name)
+	}
+	return out
+}
+
+// Generate строит схему для типа.
+func Generate(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		s := &Schema{Type: "object", Properties: &Properties{}}
+		addFields(s, t)
+		return s
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: Generate(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	default:
+		return &Schema{}
+	}
+}
+
+func addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+
+		// встроенная структура без имени в теге — поля на том же уровне
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			addFields(s, sf.Type)
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		fs := Generate(sf.Type)
+		if name == "schema_version" {
+			fs.Const = repository.SchemaVersion
+		}
+		s.Properties.set(name, fs)
+
+		omit := false
+		for _, opt := range parts[1:] {
+			omit = omit || opt == "omitempty"
+		}
+		if !omit && sf.Type.Kind() != reflect.Pointer {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
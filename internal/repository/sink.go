@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"kuperparser/internal/domain/models"
+)
+
+// ErrUnsupported — формат не умеет сохранять этот тип результата
+// (например, YML-фид для списка магазинов).
+var ErrUnsupported = errors.New("operation not supported by sink")
+
+// Sink — куда сохраняются результаты парсинга.
+type Sink interface {
+	SaveCategory(ctx context.Context, res CategoryResult) error
+	SaveStores(ctx context.Context, res StoresResult) error
+
+	// OpenCategory начинает потоковую запись категории: товары приходят
+	// пачками через WriteProducts, метаданные — в Close.
+	OpenCategory(ctx context.Context) (CategoryStream, error)
+}
+
+type CategoryStream interface {
+	WriteProducts(ctx context.Context, products []models.Product) error
+	// Close завершает запись; Products в summary игнорируется,
+	// Count — если 0, берётся по числу записанных товаров.
+	Close(summary CategoryResult) error
+	// Abort отменяет запись, частичный результат не публикуется.
+	Abort()
+}
+
+// CategoryTreeSetter — sink, которому нужно дерево категорий магазина
+// (например, YML-фиду для parentId).
+type CategoryTreeSetter interface {
+	SetCategoryTree(nodes []CategoryNode)
+}
+
+// BufferedStream — CategoryStream для форматов, которые пишутся только целиком:
+// копит товары в памяти и на Close вызывает save.
+func BufferedStream(ctx context.Context, save func(ctx context.Context, res CategoryResult) error) CategoryStream {
+	return &bufferedStream{ctx: ctx, save: save}
+}
+
+type bufferedStream struct {
+	ctx      context.Context
+	save     func(ctx context.Context, res CategoryResult) error
+	products []models.Product
+}
+
+func (b *bufferedStream) WriteProducts(ctx context.Context, products []models.Product) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.products = append(b.products, products...)
+	return nil
+}
+
+func (b *bufferedStream) Close(summary CategoryResult) error {
+	summary.Products = b.products
+	if summary.Products == nil {
+		summary.Products = []models.Product{}
+	}
+	if summary.Count == 0 {
+		summary.Count = len(b.products)
+	}
+	return b.save(b.ctx, summary)
+}
+
+func (b *bufferedStream) Abort() {
+	b.products = nil
+}
@@ -0,0 +1,220 @@
+package sinks
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kuperparser/internal/client/httpc"
+	"kuperparser/internal/client/transport"
+	"kuperparser/internal/config"
+	"kuperparser/internal/redact"
+	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/atomicfile"
+	csvfile "kuperparser/internal/repository/csv"
+	"kuperparser/internal/repository/history"
+	jsonfile "kuperparser/internal/repository/json"
+	"kuperparser/internal/repository/ndjson"
+	"kuperparser/internal/repository/sqldump"
+	"kuperparser/internal/repository/webhook"
+	"kuperparser/internal/repository/xlsx"
+	"kuperparser/internal/repository/yml"
+)
+
+// Factory создаёт sink по описанию из конфига; cfg — для общих настроек
+// формата (cli.csv, cli.sql, kuper.base_url).
+type Factory func(cfg *config.Config, spec config.SinkConfig, log *slog.Logger) (repository.Sink, error)
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Factory{
+		"json": func(cfg *config.Config, spec config.SinkConfig, log *slog.Logger) (repository.Sink, error) {
+			r := jsonfile.New(spec.Path, log)
+			r.Opts = fileOptions(cfg)
+			return r, nil
+		},
+		"ndjson": func(cfg *config.Config, spec config.SinkConfig, log *slog.Logger) (repository.Sink, error) {
+			r := ndjson.New(spec.Path, log)
+			r.Opts = fileOptions(cfg)
+			return r, nil
+		},
+		"csv": newCSV,
+		"tsv": newCSV,
+		"xlsx": func(_ *config.Config, spec config.SinkConfig, log *slog.Logger) (repository.Sink, error) {
+			return xlsx.New(spec.Path, log), nil
+		},
+		"yml": func(cfg *config.Config, spec config.SinkConfig, log *slog.Logger) (repository.Sink, error) {
+			return yml.New(spec.Path, yml.Options{ShopURL: cfg.Kuper.BaseURL}, log), nil
+		},
+		"sql": func(cfg *config.Config, spec config.SinkConfig, log *slog.Logger) (repository.Sink, error) {
+			return sqldump.New(spec.Path, sqldump.Options{
+				Dialect:   cfg.CLI.SQL.Dialect,
+				BatchSize: cfg.CLI.SQL.BatchSize,
+				Copy:      cfg.CLI.SQL.Copy,
+			}, log)
+		},
+		"webhook": newWebhook,
+	}
+)
+
+// Register добавляет (или подменяет) реализацию sink для типа name.
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[name] = f
+}
+
+// Types — зарегистрированные типы, по алфавиту.
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]string, 0, len(registry))
+	for name := range registry {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Type: явный type из описания, иначе webhook при заданном url,
+// иначе по расширению path.
+func Type(spec config.SinkConfig) string {
+	if t := strings.ToLower(strings.TrimSpace(spec.Type)); t != "" {
+		return t
+	}
+	if spec.URL != "" {
+		return "webhook"
+	}
+	// out.json.gz → json (сжатие включит atomicfile)
+	switch strings.ToLower(filepath.Ext(strings.TrimSuffix(spec.Path, ".gz"))) {
+	case ".csv":
+		return "csv"
+	case ".tsv", ".tab":
+		return "tsv"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".xlsx":
+		return "xlsx"
+	case ".yml":
+		return "yml"
+	case ".sql":
+		return "sql"
+	default:
+		return "json"
+	}
+}
+
+func New(cfg *config.Config, spec config.SinkConfig, log *slog.Logger) (repository.Sink, error) {
+	t := Type(spec)
+
+	mu.RLock()
+	f, ok := registry[t]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown sink type %q (expected %s)", t, strings.Join(Types(), "|"))
+	}
+	spec.Type = t
+	return f(cfg, spec, log)
+}
+
+// Specs — все выходы CLI: основной (cli.output_file / cli.format), затем cli.sinks.
+func Specs(cfg *config.Config) []config.SinkConfig {
+	var out []config.SinkConfig
+	if cfg.CLI.OutputFile != "" {
+		out = append(out, config.SinkConfig{Type: cfg.CLI.Format, Path: cfg.CLI.OutputFile})
+	}
+	return append(out, cfg.CLI.Sinks...)
+}
+
+// FromConfig собирает sink по конфигу CLI. Если выход один — возвращается он
+// сам, иначе tee с политикой ошибок из on_error. История цен (history.dir)
+// добавляется отдельным выходом с политикой warn: её сбой не валит выгрузку.
+func FromConfig(cfg *config.Config, log *slog.Logger) (repository.Sink, error) {
+	specs := Specs(cfg)
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no outputs configured (set cli.output_file or cli.sinks)")
+	}
+
+	targets := make([]repository.TeeTarget, 0, len(specs)+1)
+	for _, spec := range specs {
+		s, err := New(cfg, spec, log)
+		if err != nil {
+			return nil, fmt.Errorf("sink %s: %w", Name(spec), err)
+		}
+		targets = append(targets, repository.TeeTarget{
+			Name:   Name(spec),
+			Sink:   s,
+			Policy: repository.ErrorPolicy(spec.OnError),
+		})
+	}
+	if cfg.History.Dir != "" {
+		targets = append(targets, repository.TeeTarget{
+			Name:   "history " + cfg.History.Dir,
+			Sink:   history.New(cfg.History.Dir, log).Sink(cfg.Kuper.StoreID),
+			Policy: repository.PolicyWarn,
+		})
+	}
+
+	if len(targets) == 1 {
+		return targets[0].Sink, nil
+	}
+	return repository.NewTee(log, targets...), nil
+}
+
+// Name — описание выхода для логов: "csv ./out.csv", "webhook https://host/path".
+func Name(spec config.SinkConfig) string {
+	target := spec.Path
+	if target == "" {
+		target = redact.URL(spec.URL)
+	}
+	return Type(spec) + " " + target
+}
+
+func newWebhook(cfg *config.Config, spec config.SinkConfig, log *slog.Logger) (repository.Sink, error) {
+	// отдельный клиент без прокси kuper: свой сервис ходим напрямую
+	tr, err := transport.Build(transport.Options{
+		HTTPClient: httpc.New(time.Duration(cfg.HTTP.TimeoutSeconds) * time.Second),
+		Retries:    cfg.HTTP.Retries,
+		Logger:     log,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return webhook.New(webhook.Options{
+		URL:        spec.URL,
+		Secret:     spec.Secret,
+		Gzip:       spec.Gzip,
+		BatchSize:  spec.BatchSize,
+		DeadLetter: spec.DeadLetter,
+		Transport:  tr,
+	}, log)
+}
+
+func fileOptions(cfg *config.Config) atomicfile.Options {
+	return atomicfile.Options{
+		Lock:    cfg.CLI.JSON.Lock,
+		Backups: cfg.CLI.JSON.Backups,
+	}
+}
+
+func newCSV(cfg *config.Config, spec config.SinkConfig, log *slog.Logger) (repository.Sink, error) {
+	opts := csvfile.Options{
+		BOM:     cfg.CLI.CSV.BOM,
+		Columns: cfg.CLI.CSV.Columns,
+	}
+	switch cfg.CLI.CSV.Delimiter {
+	case "tab", "\\t":
+		opts.Delimiter = '\t'
+	case "":
+	default:
+		opts.Delimiter = rune(cfg.CLI.CSV.Delimiter[0])
+	}
+	if spec.Type == "tsv" {
+		opts.Delimiter = '\t'
+	}
+	return csvfile.New(spec.Path, opts, log)
+}
@@ -0,0 +1,291 @@
+package sqldump
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/atomicfile"
+)
+
+// SQL-дамп результата: CREATE TABLE IF NOT EXISTS + вставки пачками в одной
+// транзакции. Ключ снимка — fetched_at: у каждой таблицы первичный ключ от
+// него (товары — fetched_at, store_id, category_id, product_key, где
+// product_key — Product.Key: url, а без него — имя), повторная загрузка
+// того же дампа ничего не дублирует (ON CONFLICT DO NOTHING, в режиме COPY —
+// через временную таблицу), а товары разных снимков лежат рядом.
+
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
+type Options struct {
+	Dialect   string // postgres|sqlite; пусто — postgres
+	BatchSize int    // строк на один INSERT; 0 — 500
+	Copy      bool   // postgres: COPY ... FROM stdin вместо INSERT (для psql)
+}
+
+type Repo struct {
+	Path string
+	Log  *slog.Logger
+	Opts Options
+}
+
+func New(path string, opts Options, log *slog.Logger) (*Repo, error) {
+	if log == nil {
+		log = slog.Default()
+	}
+	switch opts.Dialect {
+	case "":
+		opts.Dialect = Postgres
+	case Postgres, SQLite:
+	default:
+		return nil, fmt.Errorf("sqldump repo: unknown dialect %q (expected postgres|sqlite)", opts.Dialect)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.Dialect != Postgres {
+		opts.Copy = false
+	}
+	return &Repo{Path: path, Log: log, Opts: opts}, nil
+}
+
+func (r *Repo) SaveCategory(ctx context.Context, res repository.CategoryResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := r.write(res.Incomplete, func(d *dump) {
+		d.createTables()
+
+		storeID := 0
+		if st := res.Store; st != nil {
+			storeID = st.ID
+			d.rows(storesTable, storeColumns, [][]any{storeRow(res.FetchedAt, *st)})
+		}
+		catID := 0
+		if c := res.Category; c != nil {
+			catID = c.ID
+			d.rows(categoriesTable, categoryColumns, [][]any{{res.FetchedAt, storeID, c.ID, c.Slug}})
+		}
+
+		rows := make([][]any, 0, len(res.Products))
+		for _, p := range res.Products {
+			var price any
+			if v, err := strconv.ParseFloat(p.Price, 64); err == nil {
+				price = v
+			}
+			var url any
+			if p.URL != "" {
+				url = p.URL
+			}
+			rows = append(rows, []any{res.FetchedAt, storeID, catID, p.Key(), p.Name, price, url})
+		}
+		d.rows(productsTable, productColumns, rows)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.Log.Info("sql dump saved", "path", r.Path, "dialect", r.Opts.Dialect, "count", res.Count)
+	return nil
+}
+
+func (r *Repo) OpenCategory(ctx context.Context) (repository.CategoryStream, error) {
+	return repository.BufferedStream(ctx, r.SaveCategory), nil
+}
+
+func (r *Repo) SaveStores(ctx context.Context, res repository.StoresResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := r.write(res.Incomplete, func(d *dump) {
+		d.createTables()
+		rows := make([][]any, 0, len(res.Stores))
+		for _, st := range res.Stores {
+			rows = append(rows, storeRow(res.FetchedAt, st))
+		}
+		d.rows(storesTable, storeColumns, rows)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.Log.Info("stores sql dump saved", "path", r.Path, "dialect", r.Opts.Dialect, "count", res.Count)
+	return nil
+}
+
+func (r *Repo) write(incomplete bool, body func(d *dump)) error {
+	if r.Path == "" {
+		return fmt.Errorf("sqldump repo: empty path")
+	}
+	return atomicfile.Write(r.Path, func(w io.Writer) error {
+		d := &dump{w: w, opts: r.Opts}
+		d.printf("-- kuperparser sql dump (%s)\n", r.Opts.Dialect)
+		d.printf("-- schema_version: %d\n", repository.SchemaVersion)
+		if incomplete {
+			d.printf("-- incomplete: crawl was interrupted, partial data\n")
+		}
+		d.printf("BEGIN;\n\n")
+		body(d)
+		d.printf("COMMIT;\n")
+		return d.err
+	})
+}
+
+func storeRow(fetchedAt string, st repository.StoreMeta) []any {
+	return []any{fetchedAt, st.ID, st.Name, st.Address, st.RetailerName}
+}
+
+const (
+	storesTable     = "stores"
+	categoriesTable = "categories"
+	productsTable   = "products"
+)
+
+var (
+	storeColumns    = []string{"fetched_at", "id", "name", "address", "retailer_name"}
+	categoryColumns = []string{"fetched_at", "store_id", "id", "slug"}
+	productColumns  = []string{"fetched_at", "store_id", "category_id", "product_key", "name", "price", "url"}
+)
+
+// dump — писатель с «липкой» ошибкой, чтобы не проверять каждый Fprintf.
+type dump struct {
+	w    io.Writer
+	opts Options
+	err  error
+}
+
+func (d *dump) printf(format string, args ...any) {
+	if d.err != nil {
+		return
+	}
+	_, d.err = fmt.Fprintf(d.w, format, args...)
+}
+
+func (d *dump) createTables() {
+	ts, price := "TIMESTAMPTZ", "NUMERIC(12,2)"
+	if d.opts.Dialect == SQLite {
+		ts, price = "TEXT", "REAL"
+	}
+
+	d.printf(`CREATE TABLE IF NOT EXISTS stores (
+  fetched_at %[1]s NOT NULL,
+  id INTEGER NOT NULL,
+  name TEXT,
+  address TEXT,
+  retailer_name TEXT,
+  PRIMARY KEY (fetched_at, id)
+);
+
+CREATE TABLE IF NOT EXISTS categories (
+  fetched_at %[1]s NOT NULL,
+  store_id INTEGER NOT NULL,
+  id INTEGER NOT NULL,
+  slug TEXT,
+  PRIMARY KEY (fetched_at, store_id, id)
+);
+
+CREATE TABLE IF NOT EXISTS products (
+  fetched_at %[1]s NOT NULL,
+  store_id INTEGER NOT NULL,
+  category_id INTEGER NOT NULL,
+  product_key TEXT NOT NULL,
+  name TEXT,
+  price %[2]s,
+  url TEXT,
+  PRIMARY KEY (fetched_at, store_id, category_id, product_key)
+);
+
+`, ts, price)
+}
+
+func (d *dump) rows(table string, cols []string, rows [][]any) {
+	if len(rows) == 0 {
+		return
+	}
+	if d.opts.Copy {
+		d.copyRows(table, cols, rows)
+		return
+	}
+
+	for start := 0; start < len(rows); start += d.opts.BatchSize {
+		end := min(start+d.opts.BatchSize, len(rows))
+		d.printf("INSERT INTO %s (%s) VALUES\n", table, strings.Join(cols, ", "))
+		for i, row := range rows[start:end] {
+			vals := make([]string, len(row))
+			for j, v := range row {
+				vals[j] = literal(v)
+			}
+			sep := ","
+			if i == end-start-1 {
+				sep = " ON CONFLICT DO NOTHING;"
+			}
+			d.printf("  (%s)%s\n", strings.Join(vals, ", "), sep)
+		}
+		d.printf("\n")
+	}
+}
+
+// copyRows — формат psql: COPY ... FROM stdin, строки через tab, \N — NULL.
+// У COPY нет ON CONFLICT: строки грузятся во временную таблицу и переносятся
+// INSERT ... ON CONFLICT DO NOTHING, как в режиме INSERT.
+func (d *dump) copyRows(table string, cols []string, rows [][]any) {
+	list := strings.Join(cols, ", ")
+	load := table + "_load"
+	d.printf("CREATE TEMP TABLE %s (LIKE %s INCLUDING DEFAULTS);\n", load, table)
+	d.printf("COPY %s (%s) FROM stdin;\n", load, list)
+	for _, row := range rows {
+		vals := make([]string, len(row))
+		for j, v := range row {
+			vals[j] = copyValue(v)
+		}
+		d.printf("%s\n", strings.Join(vals, "\t"))
+	}
+	d.printf("\\.\n")
+	d.printf("INSERT INTO %s (%s) SELECT %s FROM %s ON CONFLICT DO NOTHING;\n", table, list, list, load)
+	d.printf("DROP TABLE %s;\n\n", load)
+}
+
+func literal(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "NULL"
+	case int:
+		return strconv.Itoa(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case string:
+		// NUL не допускается в text у Postgres
+		s := strings.ReplaceAll(t, "\x00", "")
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	default:
+		return literal(fmt.Sprint(t))
+	}
+}
+
+var copyEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"\t", "\\t",
+	"\n", "\\n",
+	"\r", "\\r",
+	"\x00", "",
+)
+
+func copyValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return `\N`
+	case string:
+		return copyEscaper.Replace(t)
+	default:
+		return literal(t)
+	}
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// ErrorPolicy — что делать, если один из выходов tee упал.
+type ErrorPolicy string
+
+const (
+	// PolicyFail — ошибка выхода — ошибка всей записи.
+	PolicyFail ErrorPolicy = "fail"
+	// PolicyWarn — ошибка логируется, остальные выходы продолжают работу.
+	PolicyWarn ErrorPolicy = "warn"
+)
+
+type TeeTarget struct {
+	Name   string // для логов и ошибок: "json ./out.json"
+	Sink   Sink
+	Policy ErrorPolicy // пусто — PolicyFail
+}
+
+// Tee пишет один результат сразу в несколько выходов.
+// Выходы с PolicyWarn не валят запись, ErrUnsupported от них — тоже.
+type Tee struct {
+	targets []TeeTarget
+	log     *slog.Logger
+}
+
+func NewTee(log *slog.Logger, targets ...TeeTarget) *Tee {
+	if log == nil {
+		log = slog.Default()
+	}
+	for i := range targets {
+		if targets[i].Policy == "" {
+			targets[i].Policy = PolicyFail
+		}
+	}
+	return &Tee{targets: targets, log: log}
+}
+
+func (t *Tee) SaveCategory(ctx context.Context, res CategoryResult) error {
+	return t.each(func(s Sink) error { return s.SaveCategory(ctx, res) })
+}
+
+func (t *Tee) SaveStores(ctx context.Context, res StoresResult) error {
+	return t.each(func(s Sink) error { return s.SaveStores(ctx, res) })
+}
+
+// SupportsCatalog — хотя бы один выход сохранит каталог; остальные
+// при записи пропускаются с предупреждением.
+func (t *Tee) SupportsCatalog() bool {
+	for _, tg := range t.targets {
+		if SupportsCatalog(tg.Sink) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tee) SetCategoryTree(nodes []CategoryNode) {
+	for _, tg := range t.targets {
+		if ts, ok := tg.Sink.(CategoryTreeSetter); ok {
+			ts.SetCategoryTree(nodes)
+		}
+	}
+}
+
+func (t *Tee) each(fn func(s Sink) error) error {
+	var errs []error
+	for _, tg := range t.targets {
+		if err := t.check(tg, fn(tg.Sink)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// check применяет политику: возвращает ошибку, только если она должна
+// уронить запись целиком.
+func (t *Tee) check(tg TeeTarget, err error) error {
+	if err == nil {
+		return nil
+	}
+	if tg.Policy == PolicyWarn || errors.Is(err, ErrUnsupported) {
+		t.log.Warn("sink failed (continue)", "sink", tg.Name, "err", err)
+		return nil
+	}
+	return fmt.Errorf("sink %s: %w", tg.Name, err)
+}
+
+func (t *Tee) OpenCategory(ctx context.Context) (CategoryStream, error) {
+	return openTee(t, func(s Sink) (CategoryStream, error) { return s.OpenCategory(ctx) })
+}
+
+// OpenCatalog: выходы без поддержки каталога пропускаются с предупреждением.
+func (t *Tee) OpenCatalog(ctx context.Context) (CatalogStream, error) {
+	return openTee(t, func(s Sink) (CatalogStream, error) { return OpenCatalog(ctx, s) })
+}
+
+// stream — общее у CategoryStream и CatalogStream.
+type stream[P, S any] interface {
+	WriteProducts(ctx context.Context, products []P) error
+	Close(summary S) error
+	Abort()
+}
+
+func openTee[P, S any, T stream[P, S]](t *Tee, open func(s Sink) (T, error)) (*teeStream[P, S], error) {
+	ts := &teeStream[P, S]{tee: t}
+	for _, tg := range t.targets {
+		st, err := open(tg.Sink)
+		if err := t.check(tg, err); err != nil {
+			ts.Abort()
+			return nil, err
+		}
+		if any(st) != nil {
+			ts.streams = append(ts.streams, teeOpen[P, S]{target: tg, stream: st})
+		}
+	}
+	return ts, nil
+}
+
+type teeOpen[P, S any] struct {
+	target TeeTarget
+	stream stream[P, S]
+}
+
+type teeStream[P, S any] struct {
+	tee     *Tee
+	streams []teeOpen[P, S]
+}
+
+func (s *teeStream[P, S]) WriteProducts(ctx context.Context, products []P) error {
+	alive := s.streams[:0]
+	for i, o := range s.streams {
+		err := o.stream.WriteProducts(ctx, products)
+		if err == nil {
+			alive = append(alive, o)
+			continue
+		}
+		if ferr := s.tee.check(o.target, err); ferr != nil {
+			// вызывающий сделает Abort — оставляем все незакрытые потоки
+			s.streams = append(alive, s.streams[i:]...)
+			return ferr
+		}
+		// выход с warn выбывает до конца записи
+		o.stream.Abort()
+	}
+	s.streams = alive
+	return nil
+}
+
+func (s *teeStream[P, S]) Close(summary S) error {
+	var errs []error
+	for _, o := range s.streams {
+		if err := s.tee.check(o.target, o.stream.Close(summary)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.streams = nil
+	return errors.Join(errs...)
+}
+
+func (s *teeStream[P, S]) Abort() {
+	for _, o := range s.streams {
+		o.stream.Abort()
+	}
+	s.streams = nil
+}
@@ -0,0 +1,301 @@
+package webhook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"kuperparser/internal/client/transport"
+	"kuperparser/internal/domain/models"
+	"kuperparser/internal/redact"
+	"kuperparser/internal/repository"
+)
+
+// Sink отправляет результаты POST-запросом на URL.
+//
+// Тело — конверт {"schema_version","kind","batch","batches","data"}, где data —
+// CategoryResult, StoresResult или CatalogResult (при BatchSize > 0 — с частью
+// товаров/магазинов; count всегда общий). Заголовки:
+//
+//	X-Kuperparser-Delivery  — id доставки, одинаковый для всех ретраев пачки;
+//	X-Kuperparser-Timestamp — unix-время отправки;
+//	X-Kuperparser-Signature — "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)),
+//	                          body — байты как отправлены (после gzip).
+//
+// Пачка, которую не удалось доставить после ретраев, дописывается строкой
+// в dead-letter файл (NDJSON), остальные пачки всё равно отправляются.
+
+const (
+	KindCategory = "category"
+	KindStores   = "stores"
+	KindCatalog  = "catalog"
+	KindAlerts   = "alerts"
+
+	HeaderDelivery  = "X-Kuperparser-Delivery"
+	HeaderTimestamp = "X-Kuperparser-Timestamp"
+	HeaderSignature = "X-Kuperparser-Signature"
+)
+
+type Options struct {
+	URL        string
+	Secret     string // ключ HMAC; пусто — без подписи
+	Gzip       bool
+	BatchSize  int    // товаров (магазинов) в одном запросе; 0 — всё одним запросом
+	DeadLetter string // файл для недоставленных пачек; пусто — не пишем
+
+	Transport transport.Transport // с ретраями (transport.Build)
+}
+
+type envelope struct {
+	SchemaVersion int    `json:"schema_version"`
+	Kind          string `json:"kind"`
+	Batch         int    `json:"batch"`
+	Batches       int    `json:"batches"`
+	Data          any    `json:"data"`
+}
+
+type Sink struct {
+	opts Options
+	log  *slog.Logger
+}
+
+func New(opts Options, log *slog.Logger) (*Sink, error) {
+	if log == nil {
+		log = slog.Default()
+	}
+	if opts.URL == "" {
+		return nil, fmt.Errorf("webhook: empty url")
+	}
+	if opts.Transport == nil {
+		return nil, fmt.Errorf("webhook: nil transport")
+	}
+	if opts.BatchSize < 0 {
+		opts.BatchSize = 0
+	}
+	return &Sink{opts: opts, log: log}, nil
+}
+
+func (s *Sink) SaveCategory(ctx context.Context, res repository.CategoryResult) error {
+	chunks := split(len(res.Products), s.opts.BatchSize)
+	payloads := make([]any, len(chunks))
+	for i, c := range chunks {
+		part := res
+		part.Products = res.Products[c[0]:c[1]]
+		if part.Products == nil {
+			part.Products = []models.Product{}
+		}
+		payloads[i] = part
+	}
+	return s.send(ctx, KindCategory, payloads, res.Count)
+}
+
+func (s *Sink) SaveStores(ctx context.Context, res repository.StoresResult) error {
+	chunks := split(len(res.Stores), s.opts.BatchSize)
+	payloads := make([]any, len(chunks))
+	for i, c := range chunks {
+		part := res
+		part.Stores = res.Stores[c[0]:c[1]]
+		payloads[i] = part
+	}
+	return s.send(ctx, KindStores, payloads, res.Count)
+}
+
+func (s *Sink) SaveCatalog(ctx context.Context, res repository.CatalogResult) error {
+	chunks := split(len(res.Products), s.opts.BatchSize)
+	payloads := make([]any, len(chunks))
+	for i, c := range chunks {
+		part := res
+		part.Products = res.Products[c[0]:c[1]]
+		// дерево категорий — только в первой пачке
+		if i > 0 {
+			part.Categories = nil
+		}
+		payloads[i] = part
+	}
+	return s.send(ctx, KindCatalog, payloads, res.Count)
+}
+
+func (s *Sink) OpenCategory(ctx context.Context) (repository.CategoryStream, error) {
+	return repository.BufferedStream(ctx, s.SaveCategory), nil
+}
+
+func (s *Sink) OpenCatalog(ctx context.Context) (repository.CatalogStream, error) {
+	return repository.BufferedCatalog(ctx, s.SaveCatalog), nil
+}
+
+// Send отправляет произвольные данные одним конвертом kind (например, оповещения)
+// с той же подписью, ретраями и dead-letter.
+func (s *Sink) Send(ctx context.Context, kind string, data any, count int) error {
+	return s.send(ctx, kind, []any{data}, count)
+}
+
+// split режет [0,n) на отрезки по size; пустой результат — один пустой отрезок,
+// чтобы получатель всё равно узнал о прогоне.
+func split(n, size int) [][2]int {
+	if size <= 0 || n <= size {
+		return [][2]int{{0, n}}
+	}
+	var out [][2]int
+	for start := 0; start < n; start += size {
+		out = append(out, [2]int{start, min(start+size, n)})
+	}
+	return out
+}
+
+func (s *Sink) send(ctx context.Context, kind string, payloads []any, count int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var errs []error
+	for i, data := range payloads {
+		env := envelope{SchemaVersion: repository.SchemaVersion, Kind: kind, Batch: i + 1, Batches: len(payloads), Data: data}
+		raw, err := json.Marshal(env)
+		if err != nil {
+			return err
+		}
+
+		if err := s.post(ctx, raw); err != nil {
+			err = fmt.Errorf("batch %d/%d: %w", env.Batch, env.Batches, err)
+			if dlErr := s.deadLetter(env, raw, err); dlErr != nil {
+				s.log.Error("webhook dead-letter write failed", "path", s.opts.DeadLetter, "err", dlErr)
+			}
+			errs = append(errs, err)
+			// при отмене остальные пачки тоже не уйдут
+			if ctx.Err() != nil {
+				break
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("webhook %s: %w", redact.URL(s.opts.URL), errors.Join(errs...))
+	}
+
+	s.log.Info("webhook delivered", "url", redact.URL(s.opts.URL), "kind", kind, "batches", len(payloads), "count", count)
+	return nil
+}
+
+func (s *Sink) post(ctx context.Context, raw []byte) error {
+	body := raw
+	if s.opts.Gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(raw); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	// bytes.Reader: http.NewRequest выставит GetBody, и RetryTransport
+	// сможет повторить запрос с тем же телом
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.opts.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(HeaderDelivery, deliveryID())
+	req.Header.Set(HeaderTimestamp, ts)
+	if s.opts.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(s.opts.Secret, ts, body))
+	}
+
+	resp, err := s.opts.Transport.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status=%d body=%q", resp.StatusCode, bytes.TrimSpace(b))
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 32*1024))
+	return nil
+}
+
+// Sign считает подпись так же, как её должен проверять получатель.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func deliveryID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+type deadLetterRecord struct {
+	FailedAt string          `json:"failed_at"`
+	URL      string          `json:"url"`
+	Error    string          `json:"error"`
+	Kind     string          `json:"kind"`
+	Batch    int             `json:"batch"`
+	Batches  int             `json:"batches"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// deadLetter дописывает недоставленную пачку (без gzip) в конец файла.
+func (s *Sink) deadLetter(env envelope, raw []byte, cause error) error {
+	if s.opts.DeadLetter == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(deadLetterRecord{
+		FailedAt: time.Now().UTC().Format(time.RFC3339),
+		URL:      redact.URL(s.opts.URL),
+		Error:    redact.Error(cause),
+		Kind:     env.Kind,
+		Batch:    env.Batch,
+		Batches:  env.Batches,
+		Payload:  raw,
+	})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if dir := filepath.Dir(s.opts.DeadLetter); dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(s.opts.DeadLetter, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	s.log.Warn("webhook batch saved to dead-letter", "path", s.opts.DeadLetter, "batch", env.Batch, "batches", env.Batches)
+	return nil
+}
@@ -0,0 +1,218 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/atomicfile"
+)
+
+// Книга: лист "summary" со сводкой по магазину/категориям и по листу на
+// категорию (name, price, url); каталог (-all) — так же, лист на категорию. Цена — числовая ячейка, url — гиперссылка,
+// шапка закреплена. Пишется без внешних зависимостей: zip + SpreadsheetML.
+
+const (
+	summarySheet = "summary"
+
+	// лимит Excel на число гиперссылок на листе; дальше url пишется текстом
+	maxHyperlinks = 65530
+)
+
+type Repo struct {
+	Path string
+	Log  *slog.Logger
+}
+
+func New(path string, log *slog.Logger) *Repo {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Repo{Path: path, Log: log}
+}
+
+func (r *Repo) SaveCategory(ctx context.Context, res repository.CategoryResult) error {
+	return r.SaveAll(ctx, []repository.CategoryResult{res})
+}
+
+func (r *Repo) OpenCategory(ctx context.Context) (repository.CategoryStream, error) {
+	return repository.BufferedStream(ctx, r.SaveCategory), nil
+}
+
+func (r *Repo) OpenCatalog(ctx context.Context) (repository.CatalogStream, error) {
+	return repository.BufferedCatalog(ctx, r.SaveCatalog), nil
+}
+
+func (r *Repo) SaveStores(ctx context.Context, res repository.StoresResult) error {
+	return fmt.Errorf("xlsx repo: stores: %w", repository.ErrUnsupported)
+}
+
+// SaveCatalog — полный каталог (-all): лист на каждую категорию, в которой
+// найдены товары; товар из нескольких категорий есть на каждом их листе.
+func (r *Repo) SaveCatalog(ctx context.Context, res repository.CatalogResult) error {
+	return r.SaveAll(ctx, catalogSheets(res))
+}
+
+// SaveAll пишет несколько категорий в одну книгу, по листу на каждую.
+func (r *Repo) SaveAll(ctx context.Context, results []repository.CategoryResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if r.Path == "" {
+		return fmt.Errorf("xlsx repo: empty path")
+	}
+
+	names := sheetNames(results)
+	err := atomicfile.Write(r.Path, func(w io.Writer) error {
+		zw := zip.NewWriter(w)
+		if err := writeWorkbook(zw, names, results); err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for _, res := range results {
+		total += res.Count
+	}
+	r.Log.Info("xlsx saved", "path", r.Path, "sheets", len(results), "count", total)
+	return nil
+}
+
+// catalogSheets раскладывает каталог по категориям в порядке первой встречи.
+func catalogSheets(res repository.CatalogResult) []repository.CategoryResult {
+	var out []repository.CategoryResult
+	type key struct {
+		id   int
+		slug string
+	}
+	index := make(map[key]int) // категория → позиция в out; лист вне дерева — без id
+	add := func(ref repository.CategoryRef, p repository.CatalogProduct) {
+		k := key{ref.ID, ref.Slug}
+		i, ok := index[k]
+		if !ok {
+			i = len(out)
+			index[k] = i
+			cr := repository.CategoryResult{
+				FetchedAt:  res.FetchedAt,
+				Store:      res.Store,
+				Incomplete: res.Incomplete,
+			}
+			if ref.ID > 0 || ref.Slug != "" {
+				cr.Category = &repository.CategoryMeta{ID: ref.ID, Slug: ref.Slug}
+			}
+			out = append(out, cr)
+		}
+		out[i].Products = append(out[i].Products, p.Product)
+		out[i].Count++
+	}
+	for _, p := range res.Products {
+		if len(p.Categories) == 0 {
+			add(repository.CategoryRef{}, p)
+		}
+		for _, ref := range p.Categories {
+			add(ref, p)
+		}
+	}
+	return out
+}
+
+func writeWorkbook(zw *zip.Writer, names []string, results []repository.CategoryResult) error {
+	all := append([]string{summarySheet}, names...)
+
+	static := []struct {
+		name string
+		body string
+	}{
+		{"[Content_Types].xml", contentTypes(len(all))},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", workbook(all)},
+		{"xl/_rels/workbook.xml.rels", workbookRels(len(all))},
+		{"xl/styles.xml", styles},
+	}
+	for _, p := range static {
+		if err := writePart(zw, p.name, p.body); err != nil {
+			return err
+		}
+	}
+
+	summary := newSheet([]float64{12, 28, 36, 22, 22, 12, 28, 10, 10, 12})
+	summary.header("store_id", "store_name", "store_address", "retailer_name", "fetched_at", "category_id", "category_slug", "count", "priced", "incomplete")
+	for _, res := range results {
+		summary.summaryRow(res)
+	}
+	if err := summary.write(zw, 1); err != nil {
+		return err
+	}
+
+	for i, res := range results {
+		sh := newSheet([]float64{60, 12, 60})
+		sh.header("name", "price", "url")
+		for _, p := range res.Products {
+			sh.productRow(p.Name, p.Price, p.URL)
+		}
+		if err := sh.write(zw, i+2); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePart(zw *zip.Writer, name, body string) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, body)
+	return err
+}
+
+// sheetNames: slug категории (или id), с учётом ограничений Excel —
+// до 31 символа, без []:*?/\ и без повторов.
+func sheetNames(results []repository.CategoryResult) []string {
+	used := map[string]bool{strings.ToLower(summarySheet): true}
+	out := make([]string, len(results))
+	for i, res := range results {
+		base := ""
+		if res.Category != nil {
+			base = res.Category.Slug
+			if base == "" && res.Category.ID > 0 {
+				base = strconv.Itoa(res.Category.ID)
+			}
+		}
+		base = strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`[]:*?/\`, r) {
+				return '_'
+			}
+			return r
+		}, base)
+		if base == "" {
+			base = "category"
+		}
+
+		name := truncate(base, 31)
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			suffix := "_" + strconv.Itoa(n)
+			name = truncate(base, 31-len(suffix)) + suffix
+		}
+		used[strings.ToLower(name)] = true
+		out[i] = name
+	}
+	return out
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		r = r[:n]
+	}
+	return string(r)
+}
@@ -0,0 +1,172 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"kuperparser/internal/repository"
+)
+
+// индексы стилей из styles.xml (cellXfs)
+const (
+	styleDefault = 0
+	styleHeader  = 1
+	stylePrice   = 2
+	styleLink    = 3
+)
+
+type sheet struct {
+	rows  bytes.Buffer
+	n     int // текущая строка (1-based)
+	cols  []float64
+	links []string // url гиперссылок, r:id = rIdN по порядку
+	refs  []string // ячейки гиперссылок
+}
+
+func newSheet(widths []float64) *sheet {
+	return &sheet{cols: widths}
+}
+
+func (s *sheet) header(names ...string) {
+	s.beginRow()
+	for i, name := range names {
+		s.text(i, name, styleHeader)
+	}
+	s.endRow()
+}
+
+func (s *sheet) summaryRow(res repository.CategoryResult) {
+	s.beginRow()
+	if st := res.Store; st != nil {
+		s.number(0, float64(st.ID), styleDefault)
+		s.text(1, st.Name, styleDefault)
+		s.text(2, st.Address, styleDefault)
+		s.text(3, st.RetailerName, styleDefault)
+	}
+	s.text(4, res.FetchedAt, styleDefault)
+	if c := res.Category; c != nil {
+		if c.ID > 0 {
+			s.number(5, float64(c.ID), styleDefault)
+		}
+		s.text(6, c.Slug, styleDefault)
+	}
+	priced := 0
+	for _, p := range res.Products {
+		if _, ok := parsePrice(p.Price); ok {
+			priced++
+		}
+	}
+	s.number(7, float64(res.Count), styleDefault)
+	s.number(8, float64(priced), styleDefault)
+	if res.Incomplete {
+		// выгрузку остановили сигналом или дедлайном — на листе не всё
+		s.text(9, "true", styleDefault)
+	}
+	s.endRow()
+}
+
+func (s *sheet) productRow(name, price, url string) {
+	s.beginRow()
+	s.text(0, name, styleDefault)
+	if v, ok := parsePrice(price); ok {
+		s.number(1, v, stylePrice)
+	} else {
+		s.text(1, price, styleDefault)
+	}
+	if url != "" && len(s.links) < maxHyperlinks {
+		s.links = append(s.links, url)
+		s.refs = append(s.refs, cellRef(2, s.n))
+		s.text(2, url, styleLink)
+	} else {
+		s.text(2, url, styleDefault)
+	}
+	s.endRow()
+}
+
+// parsePrice: цена уже нормализована маппером ("89.99"), пустая — не число.
+func parsePrice(s string) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+func (s *sheet) beginRow() {
+	s.n++
+	fmt.Fprintf(&s.rows, `<row r="%d">`, s.n)
+}
+
+func (s *sheet) endRow() {
+	s.rows.WriteString(`</row>`)
+}
+
+func (s *sheet) text(col int, v string, style int) {
+	if v == "" {
+		return
+	}
+	fmt.Fprintf(&s.rows, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">`, cellRef(col, s.n), style)
+	_ = xml.EscapeText(&s.rows, []byte(v))
+	s.rows.WriteString(`</t></is></c>`)
+}
+
+func (s *sheet) number(col int, v float64, style int) {
+	fmt.Fprintf(&s.rows, `<c r="%s" s="%d"><v>%s</v></c>`, cellRef(col, s.n), style, strconv.FormatFloat(v, 'f', -1, 64))
+}
+
+func (s *sheet) write(zw *zip.Writer, idx int) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	// закреплённая шапка
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/><selection pane="bottomLeft"/></sheetView></sheetViews>`)
+	b.WriteString(`<cols>`)
+	for i, w := range s.cols {
+		fmt.Fprintf(&b, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, w)
+	}
+	b.WriteString(`</cols><sheetData>`)
+	b.Write(s.rows.Bytes())
+	b.WriteString(`</sheetData>`)
+	if s.n > 0 && len(s.cols) > 0 {
+		fmt.Fprintf(&b, `<autoFilter ref="A1:%s"/>`, cellRef(len(s.cols)-1, s.n))
+	}
+	if len(s.refs) > 0 {
+		b.WriteString(`<hyperlinks>`)
+		for i, ref := range s.refs {
+			fmt.Fprintf(&b, `<hyperlink ref="%s" r:id="rId%d"/>`, ref, i+1)
+		}
+		b.WriteString(`</hyperlinks>`)
+	}
+	b.WriteString(`</worksheet>`)
+
+	if err := writePart(zw, fmt.Sprintf("xl/worksheets/sheet%d.xml", idx), b.String()); err != nil {
+		return err
+	}
+	if len(s.links) == 0 {
+		return nil
+	}
+
+	var rels bytes.Buffer
+	rels.WriteString(xml.Header)
+	rels.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i, u := range s.links {
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" TargetMode="External" Target="`, i+1)
+		_ = xml.EscapeText(&rels, []byte(u))
+		rels.WriteString(`"/>`)
+	}
+	rels.WriteString(`</Relationships>`)
+	return writePart(zw, fmt.Sprintf("xl/worksheets/_rels/sheet%d.xml.rels", idx), rels.String())
+}
+
+// cellRef: (0, 1) → "A1", (27, 5) → "AB5".
+func cellRef(col, row int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name + strconv.Itoa(row)
+}
@@ -0,0 +1,250 @@
+package yml
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"time"
+
+	"kuperparser/internal/repository"
+	"kuperparser/internal/repository/atomicfile"
+)
+
+// Фид в формате YML (yml_catalog) Яндекс Маркета: shop из StoreMeta,
+// <categories> с parentId из дерева категорий, <offers> с товарами.
+// Товары без цены в фид не попадают — price у offer обязателен; остатков
+// в выгрузке нет, поэтому available — по цене: с нулевой товар недоступен.
+// categoryId у offer тоже обязателен: выгружаемая категория попадает в
+// <categories> и без дерева.
+
+type Options struct {
+	ShopURL  string // <url> магазина; обязателен в YML
+	Currency string // пусто — RUB
+}
+
+type Repo struct {
+	Path string
+	Log  *slog.Logger
+	Opts Options
+
+	// Categories — дерево категорий магазина. Если пусто, в <categories>
+	// попадают только выгружаемые категории, без parentId.
+	Categories []repository.CategoryNode
+}
+
+func (r *Repo) SetCategoryTree(nodes []repository.CategoryNode) {
+	r.Categories = nodes
+}
+
+func New(path string, opts Options, log *slog.Logger) *Repo {
+	if log == nil {
+		log = slog.Default()
+	}
+	if opts.Currency == "" {
+		opts.Currency = "RUB"
+	}
+	return &Repo{Path: path, Log: log, Opts: opts}
+}
+
+func (r *Repo) SaveCategory(ctx context.Context, res repository.CategoryResult) error {
+	return r.SaveAll(ctx, []repository.CategoryResult{res})
+}
+
+func (r *Repo) OpenCategory(ctx context.Context) (repository.CategoryStream, error) {
+	return repository.BufferedStream(ctx, r.SaveCategory), nil
+}
+
+func (r *Repo) SaveStores(ctx context.Context, res repository.StoresResult) error {
+	return fmt.Errorf("yml repo: stores: %w", repository.ErrUnsupported)
+}
+
+// SaveAll пишет один фид по нескольким категориям одного магазина.
+func (r *Repo) SaveAll(ctx context.Context, results []repository.CategoryResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if r.Path == "" {
+		return fmt.Errorf("yml repo: empty path")
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("yml repo: nothing to save")
+	}
+	for _, res := range results {
+		if res.Category == nil || res.Category.ID <= 0 {
+			return fmt.Errorf("yml repo: category id is required (categoryId of offers)")
+		}
+	}
+
+	doc, skipped := r.build(results)
+
+	err := atomicfile.Write(r.Path, func(w io.Writer) error {
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	r.Log.Info("yml saved",
+		"path", r.Path,
+		"categories", len(doc.Shop.Categories),
+		"offers", len(doc.Shop.Offers),
+		"skipped_no_price", skipped,
+	)
+	return nil
+}
+
+type catalog struct {
+	XMLName xml.Name `xml:"yml_catalog"`
+	Date    string   `xml:"date,attr"`
+	// Incomplete — выгрузку остановили сигналом или дедлайном, в фиде не все товары
+	Incomplete bool `xml:"incomplete,attr,omitempty"`
+	Shop       shop `xml:"shop"`
+}
+
+type shop struct {
+	Name       string     `xml:"name"`
+	Company    string     `xml:"company"`
+	URL        string     `xml:"url"`
+	Currencies []currency `xml:"currencies>currency"`
+	Categories []category `xml:"categories>category"`
+	Offers     []offer    `xml:"offers>offer"`
+}
+
+type currency struct {
+	ID   string `xml:"id,attr"`
+	Rate string `xml:"rate,attr"`
+}
+
+type category struct {
+	ID       int    `xml:"id,attr"`
+	ParentID int    `xml:"parentId,attr,omitempty"`
+	Name     string `xml:",chardata"`
+}
+
+type offer struct {
+	ID         string `xml:"id,attr"`
+	Available  bool   `xml:"available,attr"`
+	URL        string `xml:"url,omitempty"`
+	Price      string `xml:"price"`
+	CurrencyID string `xml:"currencyId"`
+	CategoryID int    `xml:"categoryId"`
+	Name       string `xml:"name"`
+}
+
+func (r *Repo) build(results []repository.CategoryResult) (catalog, int) {
+	first := results[0]
+
+	doc := catalog{Date: feedDate(first.FetchedAt)}
+	for _, res := range results {
+		doc.Incomplete = doc.Incomplete || res.Incomplete
+	}
+	doc.Shop.URL = r.Opts.ShopURL
+	doc.Shop.Currencies = []currency{{ID: r.Opts.Currency, Rate: "1"}}
+	if st := first.Store; st != nil {
+		doc.Shop.Name = st.Name
+		doc.Shop.Company = st.RetailerName
+		if doc.Shop.Name == "" {
+			doc.Shop.Name = strconv.Itoa(st.ID)
+		}
+	}
+	if doc.Shop.Company == "" {
+		doc.Shop.Company = doc.Shop.Name
+	}
+
+	seenCat := make(map[int]bool)
+	for _, n := range r.Categories {
+		if n.ID <= 0 || seenCat[n.ID] {
+			continue
+		}
+		seenCat[n.ID] = true
+		doc.Shop.Categories = append(doc.Shop.Categories, category{ID: n.ID, ParentID: n.ParentID, Name: nodeName(n)})
+	}
+
+	skipped := 0
+	seenOffer := make(map[string]bool)
+	for _, res := range results {
+		c := res.Category
+		if !seenCat[c.ID] {
+			seenCat[c.ID] = true
+			doc.Shop.Categories = append(doc.Shop.Categories, category{ID: c.ID, Name: nodeName(repository.CategoryNode{ID: c.ID, Slug: c.Slug})})
+		}
+
+		for _, p := range res.Products {
+			price, err := strconv.ParseFloat(p.Price, 64)
+			if err != nil {
+				skipped++
+				continue
+			}
+			id := offerID(p.URL, p.Name)
+			if seenOffer[id] {
+				continue
+			}
+			seenOffer[id] = true
+			doc.Shop.Offers = append(doc.Shop.Offers, offer{
+				ID:         id,
+				Available:  price > 0,
+				URL:        p.URL,
+				Price:      p.Price,
+				CurrencyID: r.Opts.Currency,
+				CategoryID: c.ID,
+				Name:       p.Name,
+			})
+		}
+	}
+	return doc, skipped
+}
+
+func nodeName(n repository.CategoryNode) string {
+	switch {
+	case n.Name != "":
+		return n.Name
+	case n.Slug != "":
+		return n.Slug
+	default:
+		return strconv.Itoa(n.ID)
+	}
+}
+
+// feedDate: fetched_at (RFC3339) в формате даты YML.
+func feedDate(fetchedAt string) string {
+	t, err := time.Parse(time.RFC3339, fetchedAt)
+	if err != nil {
+		t = time.Now().UTC()
+	}
+	return t.Format("2006-01-02T15:04:05-07:00")
+}
+
+var safeID = regexp.MustCompile(`^[A-Za-z0-9]{1,20}$`)
+
+// offerID: id offer — латиница/цифры до 20 символов. Берём последний сегмент
+// url товара, если он подходит, иначе — короткий хеш url (или имени).
+func offerID(rawURL, name string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Path != "" {
+		if seg := path.Base(u.Path); safeID.MatchString(seg) {
+			return seg
+		}
+	}
+	key := rawURL
+	if key == "" {
+		key = name
+	}
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:])[:16]
+}